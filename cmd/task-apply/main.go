@@ -0,0 +1,89 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command task-apply polls the readiness of already-applied manifests using
+// typed client-go status checks, in place of the per-Kind `kubectl rollout
+// status`/`kubectl wait` loop generated setup.sh scripts used to run. It
+// mirrors Helm's kube/wait pattern: one ConditionFunc per Kind, all sharing a
+// single deadline, with the last-observed status surfaced on failure so a
+// stuck resource is easy to diagnose.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func main() {
+	var kubeconfig, namespace string
+	var timeout time.Duration
+	flag.StringVar(&kubeconfig, "kubeconfig", os.Getenv("KUBECONFIG"), "Kubeconfig pointing at the target cluster")
+	flag.StringVar(&namespace, "namespace", "", "Namespace used for objects whose manifest omits metadata.namespace")
+	flag.DurationVar(&timeout, "timeout", 120*time.Second, "Deadline shared by every object's readiness poll")
+	flag.Parse()
+
+	files := flag.Args()
+	if len(files) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: task-apply [-kubeconfig path] [-namespace ns] [-timeout dur] <manifest.yaml>...")
+		os.Exit(2)
+	}
+
+	clientset, err := buildClientset(kubeconfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "task-apply: %v\n", err)
+		os.Exit(1)
+	}
+
+	var objs []manifestObject
+	for _, f := range files {
+		docs, err := readObjects(f, namespace)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "task-apply: reading %s: %v\n", f, err)
+			os.Exit(1)
+		}
+		objs = append(objs, docs...)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	w := &Waiter{clientset: clientset}
+	var failed []string
+	for _, obj := range objs {
+		if err := w.Wait(ctx, obj.Kind, obj.Namespace, obj.Name); err != nil {
+			fmt.Fprintf(os.Stderr, "task-apply: %v\n", err)
+			failed = append(failed, fmt.Sprintf("%s/%s", obj.Kind, obj.Name))
+			continue
+		}
+		fmt.Printf("task-apply: %s/%s ready\n", obj.Kind, obj.Name)
+	}
+	if len(failed) > 0 {
+		fmt.Fprintf(os.Stderr, "task-apply: %d resource(s) never became ready: %v\n", len(failed), failed)
+		os.Exit(1)
+	}
+}
+
+func buildClientset(kubeconfig string) (kubernetes.Interface, error) {
+	restCfg, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("building rest config from %s: %w", kubeconfig, err)
+	}
+	return kubernetes.NewForConfig(restCfg)
+}
@@ -0,0 +1,67 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"os"
+
+	"sigs.k8s.io/yaml"
+)
+
+// manifestObject is the subset of an applied manifest task-apply needs to
+// poll its readiness. It's read straight back off disk rather than fetched
+// live, since the caller already ran `kubectl apply` on the same file.
+type manifestObject struct {
+	Kind      string
+	Name      string
+	Namespace string
+}
+
+// readObjects parses every document in path into a manifestObject, skipping
+// anything without a kind and a metadata.name (e.g. empty documents).
+// defaultNamespace fills metadata.namespace when a document omits it, the
+// way `kubectl apply -f file -n ns` would.
+func readObjects(path, defaultNamespace string) ([]manifestObject, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var objs []manifestObject
+	for _, doc := range bytes.Split(data, []byte("---")) {
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+		var raw struct {
+			Kind     string `json:"kind"`
+			Metadata struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"metadata"`
+		}
+		if err := yaml.Unmarshal(doc, &raw); err != nil {
+			return nil, err
+		}
+		if raw.Kind == "" || raw.Metadata.Name == "" {
+			continue
+		}
+		ns := raw.Metadata.Namespace
+		if ns == "" {
+			ns = defaultNamespace
+		}
+		objs = append(objs, manifestObject{Kind: raw.Kind, Name: raw.Metadata.Name, Namespace: ns})
+	}
+	return objs, nil
+}
@@ -0,0 +1,223 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Waiter polls already-applied objects for readiness, one ConditionFunc per
+// Kind, the way Helm's kube.Wait does instead of shelling out to `kubectl
+// rollout status`/`kubectl wait`.
+type Waiter struct {
+	clientset    kubernetes.Interface
+	pollInterval time.Duration
+}
+
+// conditionFunc reports whether namespace/name is ready yet, plus a short
+// human-readable status string surfaced if the object never becomes ready
+// before the deadline. A non-nil error stops polling immediately.
+type conditionFunc func(ctx context.Context, clientset kubernetes.Interface, namespace, name string) (ready bool, status string, err error)
+
+var conditionFuncs = map[string]conditionFunc{
+	"Deployment":            deploymentReady,
+	"StatefulSet":           statefulSetReady,
+	"DaemonSet":             daemonSetReady,
+	"ReplicaSet":            replicaSetReady,
+	"Pod":                   podReady,
+	"Job":                   jobReady,
+	"PersistentVolumeClaim": pvcBound,
+	"Service":               serviceEndpointsReady,
+}
+
+// Wait polls kind/namespace/name's conditionFunc until it reports ready, ctx
+// is cancelled, or the condition returns an error. Kinds with no registered
+// conditionFunc (ConstraintTemplate, Constraint, ConfigMap, ...) are treated
+// as ready as soon as they were applied.
+func (w *Waiter) Wait(ctx context.Context, kind, namespace, name string) error {
+	cond, ok := conditionFuncs[kind]
+	if !ok {
+		return nil
+	}
+	interval := w.pollInterval
+	if interval == 0 {
+		interval = 2 * time.Second
+	}
+	var lastStatus string
+	var lastErr error
+	err := wait.PollUntilContextCancel(ctx, interval, true, func(ctx context.Context) (bool, error) {
+		ready, status, err := cond(ctx, w.clientset, namespace, name)
+		lastStatus, lastErr = status, err
+		return ready, err
+	})
+	if lastErr != nil {
+		return fmt.Errorf("%s/%s in namespace %s: %w", kind, name, namespace, lastErr)
+	}
+	if err != nil {
+		return fmt.Errorf("%s/%s in namespace %s never became ready (last status: %s): %w", kind, name, namespace, lastStatus, err)
+	}
+	return nil
+}
+
+func deploymentReady(ctx context.Context, cs kubernetes.Interface, namespace, name string) (bool, string, error) {
+	d, err := cs.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, "not created yet", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+	maxUnavailable := 0
+	if ru := d.Spec.Strategy.RollingUpdate; ru != nil && ru.MaxUnavailable != nil {
+		maxUnavailable, _ = intstr.GetScaledValueFromIntOrPercent(ru.MaxUnavailable, int(replicas), true)
+	}
+	status := fmt.Sprintf("updated=%d/%d available=%d/%d", d.Status.UpdatedReplicas, replicas, d.Status.AvailableReplicas, replicas)
+	ready := d.Status.UpdatedReplicas >= replicas && d.Status.AvailableReplicas >= replicas-int32(maxUnavailable)
+	return ready, status, nil
+}
+
+func statefulSetReady(ctx context.Context, cs kubernetes.Interface, namespace, name string) (bool, string, error) {
+	s, err := cs.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, "not created yet", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+	replicas := int32(1)
+	if s.Spec.Replicas != nil {
+		replicas = *s.Spec.Replicas
+	}
+	status := fmt.Sprintf("observedGeneration=%d/%d ready=%d/%d", s.Status.ObservedGeneration, s.Generation, s.Status.ReadyReplicas, replicas)
+	ready := s.Status.ObservedGeneration >= s.Generation && s.Status.ReadyReplicas == replicas
+	return ready, status, nil
+}
+
+func daemonSetReady(ctx context.Context, cs kubernetes.Interface, namespace, name string) (bool, string, error) {
+	d, err := cs.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, "not created yet", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+	status := fmt.Sprintf("observedGeneration=%d/%d ready=%d/%d", d.Status.ObservedGeneration, d.Generation, d.Status.NumberReady, d.Status.DesiredNumberScheduled)
+	ready := d.Status.ObservedGeneration >= d.Generation && d.Status.NumberReady == d.Status.DesiredNumberScheduled
+	return ready, status, nil
+}
+
+func replicaSetReady(ctx context.Context, cs kubernetes.Interface, namespace, name string) (bool, string, error) {
+	rs, err := cs.AppsV1().ReplicaSets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, "not created yet", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+	replicas := int32(1)
+	if rs.Spec.Replicas != nil {
+		replicas = *rs.Spec.Replicas
+	}
+	status := fmt.Sprintf("observedGeneration=%d/%d ready=%d/%d", rs.Status.ObservedGeneration, rs.Generation, rs.Status.ReadyReplicas, replicas)
+	ready := rs.Status.ObservedGeneration >= rs.Generation && rs.Status.ReadyReplicas == replicas
+	return ready, status, nil
+}
+
+func podReady(ctx context.Context, cs kubernetes.Interface, namespace, name string) (bool, string, error) {
+	p, err := cs.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, "not created yet", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+	for _, c := range p.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue, fmt.Sprintf("phase=%s ready=%s", p.Status.Phase, c.Status), nil
+		}
+	}
+	return false, fmt.Sprintf("phase=%s", p.Status.Phase), nil
+}
+
+func jobReady(ctx context.Context, cs kubernetes.Interface, namespace, name string) (bool, string, error) {
+	j, err := cs.BatchV1().Jobs(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, "not created yet", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+	for _, c := range j.Status.Conditions {
+		if c.Type == "Complete" {
+			return c.Status == corev1.ConditionTrue, fmt.Sprintf("succeeded=%d failed=%d", j.Status.Succeeded, j.Status.Failed), nil
+		}
+		if c.Type == "Failed" && c.Status == corev1.ConditionTrue {
+			return false, "", fmt.Errorf("job failed: %s", c.Message)
+		}
+	}
+	return false, fmt.Sprintf("succeeded=%d failed=%d", j.Status.Succeeded, j.Status.Failed), nil
+}
+
+func pvcBound(ctx context.Context, cs kubernetes.Interface, namespace, name string) (bool, string, error) {
+	pvc, err := cs.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, "not created yet", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+	return pvc.Status.Phase == corev1.ClaimBound, fmt.Sprintf("phase=%s", pvc.Status.Phase), nil
+}
+
+// serviceEndpointsReady requires a ready Endpoints address for ClusterIP
+// services; headless (ClusterIP "None") and ExternalName services have
+// nothing to wait for and are considered ready as soon as they exist.
+func serviceEndpointsReady(ctx context.Context, cs kubernetes.Interface, namespace, name string) (bool, string, error) {
+	svc, err := cs.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, "not created yet", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+	if svc.Spec.ClusterIP == "" || svc.Spec.ClusterIP == corev1.ClusterIPNone {
+		return true, "headless/ExternalName, nothing to wait for", nil
+	}
+	ep, err := cs.CoreV1().Endpoints(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, "no endpoints object yet", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+	addrs := 0
+	for _, subset := range ep.Subsets {
+		addrs += len(subset.Addresses)
+	}
+	return addrs > 0, fmt.Sprintf("ready addresses=%d", addrs), nil
+}
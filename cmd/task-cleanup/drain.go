@@ -0,0 +1,289 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	policyv1 "k8s.io/api/policy/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+// workloadKinds are the controller kinds task-cleanup deletes once every
+// pod in the namespace has been evicted: the controller kinds among the
+// kinds conditionFuncs in cmd/task-apply/wait.go waits on (its Pod,
+// PersistentVolumeClaim and Service entries go away with the namespace).
+var workloadKinds = []string{"Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "Job"}
+
+// drainer tears down a task namespace the way `kubectl drain` tears down a
+// node: evict every evictable pod (so PodDisruptionBudgets are honored),
+// wait for the eviction to actually take, then delete what's left.
+type drainer struct {
+	clientset kubernetes.Interface
+
+	// useEvictionV1beta1 is set once evictPod sees the policy/v1 Eviction
+	// subresource 404, so later calls skip straight to policy/v1beta1
+	// instead of re-discovering it per pod.
+	useEvictionV1beta1 bool
+}
+
+// Cleanup drains and deletes namespace, then clusterResources, returning a
+// summary of what was evicted, what was ultimately deleted, and what never
+// went away before ctx's deadline.
+func (d *drainer) Cleanup(ctx context.Context, namespace string, clusterResources []ClusterResourceRef) Summary {
+	var summary Summary
+
+	pods, err := d.evictablePods(ctx, namespace)
+	if err != nil {
+		fmt.Printf("task-cleanup: listing pods in %s: %v\n", namespace, err)
+	}
+
+	for _, pod := range pods {
+		if err := d.evictWithBackoff(ctx, namespace, pod); err != nil {
+			fmt.Printf("task-cleanup: evicting pod %s/%s: %v\n", namespace, pod, err)
+			continue
+		}
+		summary.Evicted = append(summary.Evicted, pod)
+	}
+
+	for _, pod := range pods {
+		if err := d.waitForPodGone(ctx, namespace, pod); err != nil {
+			summary.TimedOut = append(summary.TimedOut, fmt.Sprintf("pod/%s", pod))
+		}
+	}
+
+	// The grace deadline bounds eviction and the pod wait only; the
+	// deletions below still run once it has passed.
+	ctx = context.WithoutCancel(ctx)
+
+	for _, kind := range workloadKinds {
+		names, err := d.deleteWorkloads(ctx, kind, namespace)
+		if err != nil {
+			fmt.Printf("task-cleanup: deleting %ss in %s: %v\n", kind, namespace, err)
+			continue
+		}
+		for _, name := range names {
+			summary.Deleted = append(summary.Deleted, fmt.Sprintf("%s/%s", kind, name))
+		}
+	}
+
+	if err := d.deleteNamespace(ctx, namespace); err != nil {
+		summary.TimedOut = append(summary.TimedOut, fmt.Sprintf("namespace/%s", namespace))
+	} else {
+		summary.Deleted = append(summary.Deleted, fmt.Sprintf("namespace/%s", namespace))
+	}
+
+	for _, r := range clusterResources {
+		if err := d.deleteClusterResource(ctx, r); err != nil {
+			fmt.Printf("task-cleanup: deleting %s/%s: %v\n", r.Kind, r.Name, err)
+			summary.TimedOut = append(summary.TimedOut, fmt.Sprintf("%s/%s", r.Kind, r.Name))
+			continue
+		}
+		summary.Deleted = append(summary.Deleted, fmt.Sprintf("%s/%s", r.Kind, r.Name))
+	}
+
+	return summary
+}
+
+// evictablePods lists namespace's pods and drops mirror pods (static pods
+// reflected by the kubelet, which kubectl drain also never evicts) and
+// DaemonSet-owned pods (evicting them is pointless - the DaemonSet
+// controller just recreates them on the same node, and they're deleted
+// along with their controller below anyway).
+func (d *drainer) evictablePods(ctx context.Context, namespace string) ([]string, error) {
+	list, err := d.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, pod := range list.Items {
+		if _, isMirror := pod.Annotations[corev1MirrorPodAnnotation]; isMirror {
+			continue
+		}
+		if ownedByDaemonSet(pod.OwnerReferences) {
+			continue
+		}
+		names = append(names, pod.Name)
+	}
+	return names, nil
+}
+
+const corev1MirrorPodAnnotation = "kubernetes.io/config.mirror"
+
+func ownedByDaemonSet(refs []metav1.OwnerReference) bool {
+	for _, ref := range refs {
+		if ref.Kind == "DaemonSet" {
+			return true
+		}
+	}
+	return false
+}
+
+// evictWithBackoff issues a policy/v1 Eviction (falling back to
+// policy/v1beta1 once v1 reports 404, the way kubectl drain probes discovery
+// once and remembers the result) and retries 429 responses with the same
+// exponential backoff drain uses, since a PDB that's too tight to evict
+// right now often has room a few seconds later as other pods finish
+// draining.
+func (d *drainer) evictWithBackoff(ctx context.Context, namespace, pod string) error {
+	backoff := wait.Backoff{Duration: 500 * time.Millisecond, Factor: 2, Steps: 6}
+	var lastErr error
+	err := wait.ExponentialBackoffWithContext(ctx, backoff, func(ctx context.Context) (bool, error) {
+		err := d.evictPod(ctx, namespace, pod)
+		if err == nil {
+			return true, nil
+		}
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		if apierrors.IsTooManyRequests(err) {
+			lastErr = err
+			return false, nil
+		}
+		lastErr = nil
+		return false, err
+	})
+	if err != nil && lastErr != nil {
+		return fmt.Errorf("giving up after repeated 429s: %w", lastErr)
+	}
+	return err
+}
+
+func (d *drainer) evictPod(ctx context.Context, namespace, pod string) error {
+	if !d.useEvictionV1beta1 {
+		err := d.clientset.PolicyV1().Evictions(namespace).Evict(ctx, &policyv1.Eviction{
+			ObjectMeta: metav1.ObjectMeta{Name: pod, Namespace: namespace},
+		})
+		if err == nil || !apierrors.IsNotFound(err) {
+			return err
+		}
+		// policy/v1 Eviction isn't served by this apiserver (pre-1.22);
+		// remember it so the remaining pods skip straight to v1beta1.
+		d.useEvictionV1beta1 = true
+	}
+	return d.clientset.PolicyV1beta1().Evictions(namespace).Evict(ctx, &policyv1beta1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{Name: pod, Namespace: namespace},
+	})
+}
+
+// waitForPodGone polls until pod has actually disappeared, the same
+// condition kubectl drain's podDeleted waits on - a successful Eviction only
+// admits the request, it doesn't block until termination.
+func (d *drainer) waitForPodGone(ctx context.Context, namespace, pod string) error {
+	return wait.PollUntilContextCancel(ctx, 2*time.Second, true, func(ctx context.Context) (bool, error) {
+		_, err := d.clientset.CoreV1().Pods(namespace).Get(ctx, pod, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return false, nil
+	})
+}
+
+func (d *drainer) deleteWorkloads(ctx context.Context, kind, namespace string) ([]string, error) {
+	var names []string
+	switch kind {
+	case "Deployment":
+		list, err := d.clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range list.Items {
+			if err := d.clientset.AppsV1().Deployments(namespace).Delete(ctx, o.Name, metav1.DeleteOptions{}); err == nil || apierrors.IsNotFound(err) {
+				names = append(names, o.Name)
+			}
+		}
+	case "StatefulSet":
+		list, err := d.clientset.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range list.Items {
+			if err := d.clientset.AppsV1().StatefulSets(namespace).Delete(ctx, o.Name, metav1.DeleteOptions{}); err == nil || apierrors.IsNotFound(err) {
+				names = append(names, o.Name)
+			}
+		}
+	case "DaemonSet":
+		list, err := d.clientset.AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range list.Items {
+			if err := d.clientset.AppsV1().DaemonSets(namespace).Delete(ctx, o.Name, metav1.DeleteOptions{}); err == nil || apierrors.IsNotFound(err) {
+				names = append(names, o.Name)
+			}
+		}
+	case "ReplicaSet":
+		list, err := d.clientset.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range list.Items {
+			if err := d.clientset.AppsV1().ReplicaSets(namespace).Delete(ctx, o.Name, metav1.DeleteOptions{}); err == nil || apierrors.IsNotFound(err) {
+				names = append(names, o.Name)
+			}
+		}
+	case "Job":
+		list, err := d.clientset.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range list.Items {
+			if err := d.clientset.BatchV1().Jobs(namespace).Delete(ctx, o.Name, metav1.DeleteOptions{}); err == nil || apierrors.IsNotFound(err) {
+				names = append(names, o.Name)
+			}
+		}
+	}
+	return names, nil
+}
+
+func (d *drainer) deleteNamespace(ctx context.Context, namespace string) error {
+	err := d.clientset.CoreV1().Namespaces().Delete(ctx, namespace, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func (d *drainer) deleteClusterResource(ctx context.Context, r ClusterResourceRef) error {
+	// Only ClusterRole and ClusterRoleBinding are deleted here. Any other
+	// cluster-scoped kind recorded in artifacts.ClusterResources (ScopeResolver
+	// in scripts/gatekeeper-taskgen/scope.go can report more) returns an
+	// "unsupported" error, so Cleanup lists it under TimedOut.
+	switch r.Kind {
+	case "ClusterRole":
+		err := d.clientset.RbacV1().ClusterRoles().Delete(ctx, r.Name, metav1.DeleteOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	case "ClusterRoleBinding":
+		err := d.clientset.RbacV1().ClusterRoleBindings().Delete(ctx, r.Name, metav1.DeleteOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	default:
+		return fmt.Errorf("unsupported cluster-scoped kind %q", r.Kind)
+	}
+}
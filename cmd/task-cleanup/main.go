@@ -0,0 +1,100 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command task-cleanup tears down a task namespace the way `kubectl drain`
+// tears down a node: evict pods first (so PodDisruptionBudgets are honored),
+// wait for them to actually disappear, then delete workload controllers, the
+// namespace, and finally any cluster-scoped resources the task created. It
+// replaces generated cleanup.sh's flat `kubectl delete namespace`/`kubectl
+// delete <kind> <name>` list, which frequently left finalizers pending and
+// raced other tasks sharing a cluster.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// clusterResourceFlag accumulates repeated -cluster-resource Kind/Name
+// arguments, the same way stringSliceFlag does for the generator's -skip.
+type clusterResourceFlag struct {
+	values *[]ClusterResourceRef
+}
+
+func (f *clusterResourceFlag) String() string {
+	if f.values == nil {
+		return ""
+	}
+	parts := make([]string, len(*f.values))
+	for i, r := range *f.values {
+		parts[i] = r.Kind + "/" + r.Name
+	}
+	return strings.Join(parts, ",")
+}
+
+func (f *clusterResourceFlag) Set(value string) error {
+	kind, name, ok := strings.Cut(value, "/")
+	if !ok {
+		return fmt.Errorf("expected Kind/Name, got %q", value)
+	}
+	*f.values = append(*f.values, ClusterResourceRef{Kind: kind, Name: name})
+	return nil
+}
+
+func main() {
+	var kubeconfig, namespace string
+	var timeout time.Duration
+	var clusterResources []ClusterResourceRef
+	flag.StringVar(&kubeconfig, "kubeconfig", os.Getenv("KUBECONFIG"), "Kubeconfig pointing at the target cluster")
+	flag.StringVar(&namespace, "namespace", "", "Task namespace to drain and delete")
+	flag.DurationVar(&timeout, "timeout", 2*time.Minute, "Grace deadline for evicted pods to actually disappear before cleanup moves on")
+	flag.Var(&clusterResourceFlag{&clusterResources}, "cluster-resource", "Kind/Name of a cluster-scoped resource to delete after the namespace (can be repeated)")
+	flag.Parse()
+
+	if namespace == "" {
+		fmt.Fprintln(os.Stderr, "usage: task-cleanup -namespace ns [-cluster-resource Kind/Name ...] [-timeout dur]")
+		os.Exit(2)
+	}
+
+	restCfg, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "task-cleanup: building rest config from %s: %v\n", kubeconfig, err)
+		os.Exit(1)
+	}
+	clientset, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "task-cleanup: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	d := &drainer{clientset: clientset}
+	summary := d.Cleanup(ctx, namespace, clusterResources)
+
+	data, _ := json.MarshalIndent(summary, "", "  ")
+	fmt.Println(string(data))
+	if len(summary.TimedOut) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+// ClusterResourceRef identifies a cluster-scoped resource the generator
+// recorded in artifacts.ClusterResources so it can be deleted after the
+// task namespace, one per -cluster-resource argument that
+// scripts/gatekeeper-taskgen's writeScripts puts on the cleanup.sh call.
+type ClusterResourceRef struct {
+	Kind string
+	Name string
+}
+
+// Summary is Cleanup's result, written to stdout as JSON so the harness can
+// tell "cleanup failed" (TimedOut non-empty) apart from "cleanup was slow"
+// (everything eventually landed in Deleted) without scraping log text.
+type Summary struct {
+	Evicted  []string `json:"evicted"`
+	Deleted  []string `json:"deleted"`
+	TimedOut []string `json:"timed_out"`
+}
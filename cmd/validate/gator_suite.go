@@ -0,0 +1,126 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/open-policy-agent/gatekeeper/v3/pkg/gator"
+	gktest "github.com/open-policy-agent/gatekeeper/v3/pkg/gator/test"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/yaml"
+)
+
+// parseGatorFilter compiles a -run filter in gator's own "test//case"
+// regex syntax. An empty run string matches everything.
+func parseGatorFilter(run string) (gktest.Filter, error) {
+	filter, err := gktest.NewFilter(run)
+	if err != nil {
+		return gktest.Filter{}, fmt.Errorf("invalid -run filter %q: %w", run, err)
+	}
+	return filter, nil
+}
+
+// runGatorSuite builds a Gatekeeper native Suite → Test → Case document for
+// one task's template/constraint/alpha/beta and runs it through gator's own
+// gktest.Runner, the same façade the gator CLI itself is built on, instead of
+// shelling out to the gator binary and grepping its output for violations.
+func runGatorSuite(templatePath string, constraintData, alphaData, betaData []byte, referentialData [][]byte, filter gktest.Filter) (alphaViolations, betaViolations []string, err error) {
+	templateData, err := os.ReadFile(templatePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading template: %w", err)
+	}
+
+	fs := filesys.MakeFsInMemory()
+	for name, data := range map[string][]byte{
+		"template.yaml":   templateData,
+		"constraint.yaml": constraintData,
+		"alpha.yaml":      alphaData,
+		"beta.yaml":       betaData,
+	} {
+		if err := fs.WriteFile(name, data); err != nil {
+			return nil, nil, fmt.Errorf("writing %s: %w", name, err)
+		}
+	}
+
+	// Referential constraints need the objects their Rego looks up under
+	// data.inventory.* to already be cached; gator's Suite schema does this
+	// per case via a plain list of manifest paths, so seed one inventory
+	// file per object the task's artifacts carry and share it across cases.
+	var inventory []string
+	for i, data := range referentialData {
+		name := fmt.Sprintf("inventory-%02d.yaml", i)
+		if err := fs.WriteFile(name, data); err != nil {
+			return nil, nil, fmt.Errorf("writing %s: %w", name, err)
+		}
+		inventory = append(inventory, name)
+	}
+
+	suiteData, err := yaml.Marshal(map[string]interface{}{
+		"kind":       "Suite",
+		"apiVersion": "test.gatekeeper.sh/v1alpha1",
+		"metadata":   map[string]interface{}{"name": "validate"},
+		"tests": []map[string]interface{}{{
+			"name":       "validate",
+			"template":   "template.yaml",
+			"constraint": "constraint.yaml",
+			"cases": []map[string]interface{}{
+				{"name": "alpha", "object": "alpha.yaml", "inventory": inventory, "assertions": []map[string]interface{}{{"violations": "no"}}},
+				{"name": "beta", "object": "beta.yaml", "inventory": inventory, "assertions": []map[string]interface{}{{"violations": "yes"}}},
+			},
+		}},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := fs.WriteFile("suite.yaml", suiteData); err != nil {
+		return nil, nil, err
+	}
+
+	runner := gktest.Runner{FS: fs, NewClient: gator.NewOPAClient}
+	suiteResult := runner.Run(context.Background(), filter, "suite.yaml")
+	if suiteResult.Error != nil {
+		return nil, nil, suiteResult.Error
+	}
+
+	for _, tr := range suiteResult.TestResults {
+		for _, cr := range tr.CaseResults {
+			switch cr.CaseName {
+			case "alpha":
+				// Assertion is "violations: no"; a non-nil CaseResult error
+				// means the assertion failed, i.e. the constraint did
+				// violate alpha after all.
+				if cr.Error != nil {
+					alphaViolations = []string{cr.Error.Error()}
+				}
+			case "beta":
+				// Assertion is "violations: yes"; it passing means the
+				// constraint violated beta as expected. gator doesn't hand
+				// back the individual violation messages for a passing
+				// assertion, so record a single structured marker rather
+				// than a grepped CLI line.
+				if cr.Error == nil {
+					betaViolations = []string{"constraint violated beta, as expected"}
+				} else {
+					betaViolations = nil
+				}
+			}
+		}
+	}
+
+	return alphaViolations, betaViolations, nil
+}
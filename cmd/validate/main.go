@@ -16,15 +16,16 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"sort"
 	"strings"
 	"sync"
 
+	gktest "github.com/open-policy-agent/gatekeeper/v3/pkg/gator/test"
 	"sigs.k8s.io/yaml"
 )
 
@@ -35,15 +36,47 @@ type ValidateConfig struct {
 	TaskPattern string
 	Parallel    int
 	Verbose     bool
+	Mode        string // "gator", "vap", or "both"
+	Kubeconfig  string // cluster used for VAP validation; defaults to $KUBECONFIG
+	Run         string // gator-style "test//case" filter regex, e.g. "-run alpha"
 }
 
 // ValidationResult holds the result of validating a single task.
 type ValidationResult struct {
 	TaskName        string
+	Mode            string
 	Passed          bool
 	AlphaViolations []string
 	BetaViolations  []string
-	Error           string
+	// VAPTested is true when a ValidatingAdmissionPolicy was actually
+	// evaluated for this task (mode vap/both and a CEL validations block or
+	// sibling vap.yaml was found). VAPAlphaViolations/VAPBetaViolations are
+	// only meaningful when this is true.
+	VAPTested          bool
+	VAPAlphaViolations []string
+	VAPBetaViolations  []string
+	// Referential is true when the template's Rego reads data.inventory.*,
+	// meaning it needs cached cluster state to evaluate correctly.
+	// MissingSyncGVKs lists GVKs the Rego references that neither a
+	// hand-written sync.yaml nor the synthesized one declares.
+	Referential     bool
+	MissingSyncGVKs []string
+	// MutationDiff lists the dotted field paths where applying a
+	// gk-mutation-* task's mutators produced an object that differs from
+	// the task's expected post-mutation resource. Only set for Mode
+	// "mutation"; empty (and Passed true) means the mutators reproduced it
+	// exactly outside mutationIgnoredFields.
+	MutationDiff []string
+	Error        string
+}
+
+// validateAnyTask dispatches a task directory to the mutation validator or
+// the constraint validator, based on whether it's a gk-mutation-* task.
+func validateAnyTask(taskDir string, config ValidateConfig, vap *vapCluster, filter gktest.Filter) ValidationResult {
+	if isMutationTask(taskDir) {
+		return validateMutationTask(taskDir, config)
+	}
+	return validateTask(taskDir, config, vap, filter)
 }
 
 func main() {
@@ -51,11 +84,12 @@ func main() {
 		TasksDir:   "./tasks",
 		LibraryDir: "./.gatekeeper-library",
 		Parallel:   8,
+		Mode:       "gator",
 	}
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: %s [options]\n\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "Validate Gatekeeper tasks using gator CLI.\n\n")
+		fmt.Fprintf(os.Stderr, "Validate Gatekeeper tasks using gator's test engine in-process.\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		flag.PrintDefaults()
 	}
@@ -65,6 +99,9 @@ func main() {
 	flag.StringVar(&config.TaskPattern, "task", config.TaskPattern, "Pattern to filter tasks (e.g. 'allowedrepos')")
 	flag.IntVar(&config.Parallel, "parallel", config.Parallel, "Number of parallel validations (use 1 for sequential)")
 	flag.BoolVar(&config.Verbose, "verbose", config.Verbose, "Enable verbose output")
+	flag.StringVar(&config.Mode, "mode", config.Mode, "Validation mode: gator, vap, or both")
+	flag.StringVar(&config.Kubeconfig, "kubeconfig", "", "Kubeconfig used for ValidatingAdmissionPolicy validation (mode vap/both); defaults to $KUBECONFIG")
+	flag.StringVar(&config.Run, "run", "", "Filter cases by gator's test//case regex syntax, e.g. 'validate//beta'")
 	flag.Parse()
 
 	if err := run(config); err != nil {
@@ -74,11 +111,27 @@ func main() {
 }
 
 func run(config ValidateConfig) error {
-	// Check gator is available
-	if err := checkGatorInstalled(); err != nil {
+	switch config.Mode {
+	case "gator", "vap", "both":
+	default:
+		return fmt.Errorf("invalid -mode %q: must be gator, vap, or both", config.Mode)
+	}
+
+	filter, err := parseGatorFilter(config.Run)
+	if err != nil {
 		return err
 	}
 
+	// Connect to a live cluster for VAP validation; gator test doesn't need one
+	var vap *vapCluster
+	if config.Mode != "gator" {
+		vc, err := connectVAPCluster(config.Kubeconfig)
+		if err != nil {
+			return fmt.Errorf("connecting for VAP validation: %w", err)
+		}
+		vap = vc
+	}
+
 	// Validate library exists
 	if _, err := os.Stat(config.LibraryDir); os.IsNotExist(err) {
 		return fmt.Errorf("gatekeeper library not found at %s\nRun generate.py first to clone the library", config.LibraryDir)
@@ -113,14 +166,18 @@ func run(config ValidateConfig) error {
 	// Run validation
 	var results []ValidationResult
 	if config.Parallel > 1 {
-		results = validateTasksParallel(tasks, config)
+		results = validateTasksParallel(tasks, config, vap, filter)
 	} else {
-		results = validateTasksSequential(tasks, config)
+		results = validateTasksSequential(tasks, config, vap, filter)
 	}
 
 	// Print summary
 	printValidationSummary(results)
 
+	if err := writeSuiteReport(config.TasksDir, results); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to write suite report: %v\n", err)
+	}
+
 	// Return error if any failed
 	for _, r := range results {
 		if !r.Passed {
@@ -131,16 +188,6 @@ func run(config ValidateConfig) error {
 	return nil
 }
 
-func checkGatorInstalled() error {
-	cmd := exec.Command("gator", "version")
-	output, err := cmd.Output()
-	if err != nil {
-		return fmt.Errorf("gator CLI not found. Install it from: https://open-policy-agent.github.io/gatekeeper/website/docs/gator/")
-	}
-	fmt.Printf("Using gator: %s\n", strings.TrimSpace(string(output)))
-	return nil
-}
-
 func findGatekeeperTasks(tasksDir string) ([]string, error) {
 	gkDir := filepath.Join(tasksDir, "gatekeeper")
 	if _, err := os.Stat(gkDir); os.IsNotExist(err) {
@@ -167,12 +214,12 @@ func findGatekeeperTasks(tasksDir string) ([]string, error) {
 	return tasks, nil
 }
 
-func findPolicyFiles(taskDir, libraryDir string) (templatePath, constraintPath string, err error) {
+func findPolicyFiles(taskDir, libraryDir string) (templatePath, constraintPath, vapPath, syncPath string, err error) {
 	// Parse task name: gk-general-<policyname>-<index>
 	taskName := filepath.Base(taskDir)
 	parts := strings.Split(taskName, "-")
 	if len(parts) < 4 {
-		return "", "", fmt.Errorf("invalid task name format: %s", taskName)
+		return "", "", "", "", fmt.Errorf("invalid task name format: %s", taskName)
 	}
 
 	policyName := strings.Join(parts[2:len(parts)-1], "-")
@@ -184,7 +231,7 @@ func findPolicyFiles(taskDir, libraryDir string) (templatePath, constraintPath s
 		generalDir := filepath.Join(libraryDir, "library", "general")
 		entries, err := os.ReadDir(generalDir)
 		if err != nil {
-			return "", "", err
+			return "", "", "", "", err
 		}
 		for _, entry := range entries {
 			if strings.EqualFold(
@@ -198,13 +245,13 @@ func findPolicyFiles(taskDir, libraryDir string) (templatePath, constraintPath s
 	}
 
 	if _, err := os.Stat(policyDir); os.IsNotExist(err) {
-		return "", "", fmt.Errorf("policy directory not found for %s", policyName)
+		return "", "", "", "", fmt.Errorf("policy directory not found for %s", policyName)
 	}
 
 	// Find template
 	templatePath = filepath.Join(policyDir, "template.yaml")
 	if _, err := os.Stat(templatePath); os.IsNotExist(err) {
-		return "", "", fmt.Errorf("template.yaml not found in %s", policyDir)
+		return "", "", "", "", fmt.Errorf("template.yaml not found in %s", policyDir)
 	}
 
 	// Find first sample's constraint
@@ -222,10 +269,32 @@ func findPolicyFiles(taskDir, libraryDir string) (templatePath, constraintPath s
 	}
 
 	if constraintPath == "" {
-		return "", "", fmt.Errorf("no constraint.yaml found in samples for %s", policyName)
+		return "", "", "", "", fmt.Errorf("no constraint.yaml found in samples for %s", policyName)
+	}
+
+	// A sibling vap.yaml/validatingadmissionpolicy.yaml, if the upstream
+	// library ships a hand-written VAP for this policy, takes priority over
+	// synthesizing one from the template's CEL validations block.
+	for _, name := range []string{"vap.yaml", "validatingadmissionpolicy.yaml"} {
+		p := filepath.Join(policyDir, name)
+		if _, err := os.Stat(p); err == nil {
+			vapPath = p
+			break
+		}
+	}
+
+	// A sibling sync.yaml/config.yaml, if the upstream library ships a
+	// hand-written Config/SyncSet for this policy, takes priority over
+	// synthesizing one from the template's syncOnly hints.
+	for _, name := range []string{"sync.yaml", "config.yaml"} {
+		p := filepath.Join(policyDir, name)
+		if _, err := os.Stat(p); err == nil {
+			syncPath = p
+			break
+		}
 	}
 
-	return templatePath, constraintPath, nil
+	return templatePath, constraintPath, vapPath, syncPath, nil
 }
 
 // patchConstraintYAML removes namespace restrictions from a constraint YAML.
@@ -283,68 +352,40 @@ func addNamespaceToResourceYAML(data []byte, namespace string) ([]byte, error) {
 	return bytes.Join(results, []byte("\n---\n")), nil
 }
 
-// runGatorTest runs gator test and returns violation messages.
-func runGatorTest(templatePath, constraintData, resourceData []byte) ([]string, error) {
-	// Create temp directory for files
-	tmpDir, err := os.MkdirTemp("", "gator-test-*")
-	if err != nil {
-		return nil, err
-	}
-	defer os.RemoveAll(tmpDir)
+func validateTask(taskDir string, config ValidateConfig, vap *vapCluster, filter gktest.Filter) ValidationResult {
+	taskName := filepath.Base(taskDir)
+	result := ValidationResult{TaskName: taskName, Mode: config.Mode}
 
-	// Write constraint
-	constraintPath := filepath.Join(tmpDir, "constraint.yaml")
-	if err := os.WriteFile(constraintPath, constraintData, 0644); err != nil {
-		return nil, err
+	// Find policy files
+	templatePath, constraintPath, vapPath, syncPath, err := findPolicyFiles(taskDir, config.LibraryDir)
+	if err != nil {
+		result.Error = err.Error()
+		return result
 	}
 
-	// Write resources
-	resourcePath := filepath.Join(tmpDir, "resources.yaml")
-	if err := os.WriteFile(resourcePath, resourceData, 0644); err != nil {
-		return nil, err
+	templateData, err := os.ReadFile(templatePath)
+	if err != nil {
+		result.Error = fmt.Sprintf("reading template: %v", err)
+		return result
 	}
 
-	// Run gator test
-	cmd := exec.Command("gator", "test",
-		"-f", string(templatePath),
-		"-f", constraintPath,
-		"-f", resourcePath,
-	)
-
-	output, err := cmd.CombinedOutput()
-
-	// Parse output for violations
-	var violations []string
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-		// Ignore warnings
-		if strings.Contains(line, "WARNING") {
-			continue
+	// Referential constraints (uniqueness, cross-namespace lookups, ...) read
+	// data.inventory.* in their Rego and silently evaluate as "no match"
+	// without it. Surface that need in the result instead of letting it fail
+	// opaquely as an unexplained missing violation.
+	if requiredGVKs := requiredInventoryGVKs(templateData); len(requiredGVKs) > 0 {
+		result.Referential = true
+		syncConfig, err := loadOrSynthesizeSyncConfig(syncPath, templateData)
+		if err != nil {
+			result.Error = fmt.Sprintf("resolving sync config: %v", err)
+			return result
 		}
-		violations = append(violations, line)
-	}
-
-	// gator returns non-zero if there are violations (or errors)
-	// We consider it has violations if exit code != 0 OR we found output lines
-	if err != nil || len(violations) > 0 {
-		return violations, nil
+		result.MissingSyncGVKs = missingSyncGVKs(requiredGVKs, syncConfig)
 	}
 
-	return nil, nil
-}
-
-func validateTask(taskDir string, config ValidateConfig) ValidationResult {
-	taskName := filepath.Base(taskDir)
-	result := ValidationResult{TaskName: taskName}
-
-	// Find policy files
-	templatePath, constraintPath, err := findPolicyFiles(taskDir, config.LibraryDir)
+	referentialData, err := findReferentialData(taskDir)
 	if err != nil {
-		result.Error = err.Error()
+		result.Error = fmt.Sprintf("finding referential data: %v", err)
 		return result
 	}
 
@@ -397,31 +438,50 @@ func validateTask(taskDir string, config ValidateConfig) ValidationResult {
 		return result
 	}
 
-	// Test alpha (should have NO violations)
-	alphaViolations, err := runGatorTest([]byte(templatePath), patchedConstraint, patchedAlpha)
-	if err != nil {
-		result.Error = fmt.Sprintf("testing alpha: %v", err)
-		return result
+	if config.Mode != "vap" {
+		alphaViolations, betaViolations, err := runGatorSuite(templatePath, patchedConstraint, patchedAlpha, patchedBeta, referentialData, filter)
+		if err != nil {
+			result.Error = fmt.Sprintf("running gator suite: %v", err)
+			return result
+		}
+		result.AlphaViolations = alphaViolations
+		result.BetaViolations = betaViolations
 	}
-	result.AlphaViolations = alphaViolations
 
-	// Test beta (SHOULD have violations)
-	betaViolations, err := runGatorTest([]byte(templatePath), patchedConstraint, patchedBeta)
-	if err != nil {
-		result.Error = fmt.Sprintf("testing beta: %v", err)
-		return result
+	if config.Mode != "gator" {
+		vapAlpha, vapBeta, err := runVAPTest(vap, taskName, templatePath, vapPath, patchedAlpha, patchedBeta)
+		if err != nil {
+			if config.Mode == "vap" {
+				result.Error = fmt.Sprintf("testing VAP: %v", err)
+				return result
+			}
+			// In "both" mode a missing CEL validations block just means
+			// this policy has no VAP equivalent yet - that's a finding in
+			// itself (see printValidationSummary), not a task failure.
+			if config.Verbose {
+				fmt.Printf("  %s: VAP unavailable: %v\n", taskName, err)
+			}
+		} else {
+			result.VAPTested = true
+			result.VAPAlphaViolations = vapAlpha
+			result.VAPBetaViolations = vapBeta
+		}
 	}
-	result.BetaViolations = betaViolations
 
-	// Pass = alpha has NO violations AND beta HAS violations
-	alphaOK := len(alphaViolations) == 0
-	betaOK := len(betaViolations) > 0
-	result.Passed = alphaOK && betaOK
+	// Pass = alpha has NO violations AND beta HAS violations. In "both" mode
+	// gator remains the authoritative pass/fail signal; VAP results are
+	// surfaced separately as a compatibility label.
+	switch config.Mode {
+	case "vap":
+		result.Passed = len(result.VAPAlphaViolations) == 0 && len(result.VAPBetaViolations) > 0
+	default:
+		result.Passed = len(result.AlphaViolations) == 0 && len(result.BetaViolations) > 0
+	}
 
 	return result
 }
 
-func validateTasksSequential(tasks []string, config ValidateConfig) []ValidationResult {
+func validateTasksSequential(tasks []string, config ValidateConfig, vap *vapCluster, filter gktest.Filter) []ValidationResult {
 	var results []ValidationResult
 
 	for _, taskDir := range tasks {
@@ -430,7 +490,7 @@ func validateTasksSequential(tasks []string, config ValidateConfig) []Validation
 			fmt.Printf("Validating: %s\n", taskName)
 		}
 
-		result := validateTask(taskDir, config)
+		result := validateAnyTask(taskDir, config, vap, filter)
 		results = append(results, result)
 
 		status := "FAIL"
@@ -443,7 +503,7 @@ func validateTasksSequential(tasks []string, config ValidateConfig) []Validation
 	return results
 }
 
-func validateTasksParallel(tasks []string, config ValidateConfig) []ValidationResult {
+func validateTasksParallel(tasks []string, config ValidateConfig, vap *vapCluster, filter gktest.Filter) []ValidationResult {
 	results := make([]ValidationResult, len(tasks))
 	var wg sync.WaitGroup
 	sem := make(chan struct{}, config.Parallel)
@@ -456,7 +516,7 @@ func validateTasksParallel(tasks []string, config ValidateConfig) []ValidationRe
 			sem <- struct{}{}
 			defer func() { <-sem }()
 
-			result := validateTask(td, config)
+			result := validateAnyTask(td, config, vap, filter)
 
 			mu.Lock()
 			results[idx] = result
@@ -520,6 +580,96 @@ func printValidationSummary(results []ValidationResult) {
 			}
 		}
 	}
+
+	if len(results) > 0 && results[0].Mode == "both" {
+		printVAPCompatibilitySummary(results)
+	}
+
+	printReferentialSummary(results)
+}
+
+// printReferentialSummary calls out tasks whose templates are referential
+// (read data.inventory.*) so a reader can tell that from an opaque failure.
+// Tasks with MissingSyncGVKs are flagged separately since their result, pass
+// or fail, isn't trustworthy until the sync config covers the GVKs they need.
+func printReferentialSummary(results []ValidationResult) {
+	var referential, missing []ValidationResult
+	for _, r := range results {
+		if !r.Referential {
+			continue
+		}
+		referential = append(referential, r)
+		if len(r.MissingSyncGVKs) > 0 {
+			missing = append(missing, r)
+		}
+	}
+	if len(referential) == 0 {
+		return
+	}
+
+	fmt.Printf("\n%s\n", strings.Repeat("=", 60))
+	fmt.Printf("Referential: %d/%d tasks need cached cluster state (data.inventory.*)\n", len(referential), len(results))
+	fmt.Printf("%s\n", strings.Repeat("=", 60))
+
+	if len(missing) > 0 {
+		fmt.Printf("\nMISSING SYNC COVERAGE (%d):\n", len(missing))
+		for _, r := range missing {
+			fmt.Printf("  %s: %s\n", r.TaskName, strings.Join(r.MissingSyncGVKs, ", "))
+		}
+	}
+}
+
+// vapCompatibility labels a task by comparing its gator result against its
+// ValidatingAdmissionPolicy result: "vap-compatible" when both engines reach
+// the same outcome (both pass or both fail), "rego-only" when no VAP could be
+// evaluated for it, and
+// "divergent" when the two engines disagree (e.g. the synthesized VAP denies
+// alpha, or fails to deny beta, when Rego gets it right).
+func vapCompatibility(r ValidationResult) string {
+	if !r.VAPTested {
+		return "rego-only"
+	}
+	gatorPassed := len(r.AlphaViolations) == 0 && len(r.BetaViolations) > 0
+	vapPassed := len(r.VAPAlphaViolations) == 0 && len(r.VAPBetaViolations) > 0
+	if gatorPassed == vapPassed {
+		return "vap-compatible"
+	}
+	return "divergent"
+}
+
+func printVAPCompatibilitySummary(results []ValidationResult) {
+	counts := map[string]int{}
+	var divergent []string
+	for _, r := range results {
+		label := vapCompatibility(r)
+		counts[label]++
+		if label == "divergent" {
+			divergent = append(divergent, r.TaskName)
+		}
+	}
+
+	fmt.Printf("\n%s\n", strings.Repeat("=", 60))
+	fmt.Printf("VAP Compatibility: %d vap-compatible, %d rego-only, %d divergent\n",
+		counts["vap-compatible"], counts["rego-only"], counts["divergent"])
+	fmt.Printf("%s\n", strings.Repeat("=", 60))
+
+	if len(divergent) > 0 {
+		fmt.Printf("\nDIVERGENT (gator and VAP disagree) (%d):\n", len(divergent))
+		for _, name := range divergent {
+			fmt.Printf("  %s\n", name)
+		}
+	}
+}
+
+// writeSuiteReport drops a machine-readable copy of results next to the
+// human-readable summary printed to stdout, so CI and other tooling can
+// consume gator suite results without scraping terminal output.
+func writeSuiteReport(tasksDir string, results []ValidationResult) error {
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(tasksDir, "gatekeeper", "suite-report.json"), data, 0644)
 }
 
 func countPassed(results []ValidationResult) int {
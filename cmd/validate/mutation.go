@@ -0,0 +1,395 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// mutationIgnoredFields are metadata/status fields a mutator never sets and
+// the cluster fills in on its own, so a diff against the expected object
+// shouldn't fail a task over them.
+var mutationIgnoredFields = map[string]bool{
+	"metadata.resourceVersion":   true,
+	"metadata.generation":        true,
+	"metadata.uid":               true,
+	"metadata.creationTimestamp": true,
+	"metadata.managedFields":     true,
+	"status":                     true,
+}
+
+// findMutatorFile locates the Assign/AssignMetadata/ModifySet/AssignImage
+// manifest for a gk-mutation-<policyname>-<index> task, mirroring how
+// findPolicyFiles locates a constraint template's policy directory.
+func findMutatorFile(taskDir, libraryDir string) (mutatorPath string, err error) {
+	taskName := filepath.Base(taskDir)
+	parts := strings.Split(taskName, "-")
+	if len(parts) < 4 {
+		return "", fmt.Errorf("invalid task name format: %s", taskName)
+	}
+	policyName := strings.Join(parts[2:len(parts)-1], "-")
+
+	policyDir := filepath.Join(libraryDir, "library", "mutations", policyName)
+	if _, err := os.Stat(policyDir); os.IsNotExist(err) {
+		mutationsDir := filepath.Join(libraryDir, "library", "mutations")
+		entries, err := os.ReadDir(mutationsDir)
+		if err != nil {
+			return "", err
+		}
+		for _, entry := range entries {
+			if strings.EqualFold(
+				strings.ReplaceAll(entry.Name(), "-", ""),
+				strings.ReplaceAll(policyName, "-", ""),
+			) {
+				policyDir = filepath.Join(mutationsDir, entry.Name())
+				break
+			}
+		}
+	}
+
+	mutatorPath = filepath.Join(policyDir, "mutator.yaml")
+	if _, err := os.Stat(mutatorPath); os.IsNotExist(err) {
+		return "", fmt.Errorf("mutator.yaml not found for %s", policyName)
+	}
+	return mutatorPath, nil
+}
+
+// isMutationTask reports whether taskDir is a gk-mutation-* task, the
+// mutation counterpart of findGatekeeperTasks's gk-general-* constraint
+// tasks (both share the "gk-" discovery prefix in findGatekeeperTasks).
+func isMutationTask(taskDir string) bool {
+	return strings.HasPrefix(filepath.Base(taskDir), "gk-mutation-")
+}
+
+// validateMutationTask applies a task's mutators to its input resource and
+// diffs the result against the expected post-mutation resource the task
+// ships. It passes when the two match outside mutationIgnoredFields.
+func validateMutationTask(taskDir string, config ValidateConfig) ValidationResult {
+	taskName := filepath.Base(taskDir)
+	result := ValidationResult{TaskName: taskName, Mode: "mutation"}
+
+	// Prefer the mutator the task shipped in its own artifacts, the same
+	// way validateTask prefers a task-local constraint.yaml, falling back
+	// to the library's copy.
+	mutatorPath := filepath.Join(taskDir, "artifacts", "mutator.yaml")
+	if _, err := os.Stat(mutatorPath); os.IsNotExist(err) {
+		p, err := findMutatorFile(taskDir, config.LibraryDir)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		mutatorPath = p
+	}
+
+	mutatorData, err := os.ReadFile(mutatorPath)
+	if err != nil {
+		result.Error = fmt.Sprintf("reading mutator: %v", err)
+		return result
+	}
+	mutators, err := parseMutators(mutatorData)
+	if err != nil {
+		result.Error = fmt.Sprintf("parsing mutator: %v", err)
+		return result
+	}
+
+	inputData, err := os.ReadFile(filepath.Join(taskDir, "artifacts", "resource-input.yaml"))
+	if err != nil {
+		result.Error = fmt.Sprintf("reading input resource: %v", err)
+		return result
+	}
+	var input map[string]interface{}
+	if err := yaml.Unmarshal(inputData, &input); err != nil {
+		result.Error = fmt.Sprintf("parsing input resource: %v", err)
+		return result
+	}
+
+	expectedData, err := os.ReadFile(filepath.Join(taskDir, "artifacts", "resource-expected.yaml"))
+	if err != nil {
+		result.Error = fmt.Sprintf("reading expected resource: %v", err)
+		return result
+	}
+	var expected map[string]interface{}
+	if err := yaml.Unmarshal(expectedData, &expected); err != nil {
+		result.Error = fmt.Sprintf("parsing expected resource: %v", err)
+		return result
+	}
+
+	actual, err := applyMutators(mutators, input)
+	if err != nil {
+		result.Error = fmt.Sprintf("applying mutators: %v", err)
+		return result
+	}
+
+	result.MutationDiff = diffMutation(actual, expected, "")
+	result.Passed = len(result.MutationDiff) == 0
+	return result
+}
+
+// parseMutators splits a mutator.yaml (one or more Assign/AssignMetadata/
+// ModifySet/AssignImage documents) into individual mutator objects.
+func parseMutators(data []byte) ([]map[string]interface{}, error) {
+	var mutators []map[string]interface{}
+	for _, doc := range bytes.Split(data, []byte("\n---")) {
+		doc = bytes.TrimSpace(doc)
+		if len(doc) == 0 {
+			continue
+		}
+		var obj map[string]interface{}
+		if err := yaml.Unmarshal(doc, &obj); err != nil {
+			return nil, err
+		}
+		mutators = append(mutators, obj)
+	}
+	if len(mutators) == 0 {
+		return nil, fmt.Errorf("no mutator documents found")
+	}
+	return mutators, nil
+}
+
+// applyMutators runs each mutator over object in order, the way Gatekeeper's
+// webhook applies every matching mutator to an incoming request. It mutates
+// object in place and returns it.
+func applyMutators(mutators []map[string]interface{}, object map[string]interface{}) (map[string]interface{}, error) {
+	current := object
+	for _, m := range mutators {
+		kind, _ := m["kind"].(string)
+		spec, _ := m["spec"].(map[string]interface{})
+		location, _ := spec["location"].(string)
+		if location == "" {
+			return nil, fmt.Errorf("mutator %s has no spec.location", kind)
+		}
+		segments := parseMutationLocation(location)
+
+		var err error
+		switch kind {
+		case "Assign":
+			value := assignValue(spec)
+			err = setAtLocation(current, segments, func(interface{}) (interface{}, bool) { return value, true })
+		case "AssignMetadata":
+			value := assignValue(spec)
+			err = setAtLocation(current, segments, func(interface{}) (interface{}, bool) { return value, true })
+		case "AssignImage":
+			err = setAtLocation(current, segments, func(existing interface{}) (interface{}, bool) {
+				return assignImage(spec, existing), true
+			})
+		case "ModifySet":
+			err = setAtLocation(current, segments, func(existing interface{}) (interface{}, bool) {
+				return modifySet(spec, existing), true
+			})
+		default:
+			return nil, fmt.Errorf("unsupported mutator kind %q", kind)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("applying %s: %w", kind, err)
+		}
+	}
+	return current, nil
+}
+
+func assignValue(spec map[string]interface{}) interface{} {
+	params, _ := spec["parameters"].(map[string]interface{})
+	assign, _ := params["assign"].(map[string]interface{})
+	return assign["value"]
+}
+
+func assignImage(spec map[string]interface{}, existing interface{}) interface{} {
+	image, _ := existing.(string)
+	params, _ := spec["parameters"].(map[string]interface{})
+	if domain, ok := params["assignDomain"].(string); ok && domain != "" {
+		if i := strings.Index(image, "/"); i >= 0 && strings.ContainsAny(image[:i], ".:") {
+			image = domain + image[i:]
+		} else {
+			image = domain + "/" + image
+		}
+	}
+	if tag, ok := params["assignTag"].(string); ok && tag != "" {
+		if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+			image = image[:i] + tag
+		} else {
+			image = image + tag
+		}
+	}
+	return image
+}
+
+func modifySet(spec map[string]interface{}, existing interface{}) interface{} {
+	params, _ := spec["parameters"].(map[string]interface{})
+	operation, _ := params["operation"].(string)
+	values, _ := params["values"].(map[string]interface{})
+	fromList, _ := values["fromList"].([]interface{})
+
+	var current []interface{}
+	if l, ok := existing.([]interface{}); ok {
+		current = l
+	}
+
+	switch operation {
+	case "prune":
+		var result []interface{}
+		for _, v := range current {
+			if !containsValue(fromList, v) {
+				result = append(result, v)
+			}
+		}
+		return result
+	default: // "merge"
+		result := append([]interface{}{}, current...)
+		for _, v := range fromList {
+			if !containsValue(result, v) {
+				result = append(result, v)
+			}
+		}
+		return result
+	}
+}
+
+func containsValue(list []interface{}, v interface{}) bool {
+	for _, e := range list {
+		if fmt.Sprint(e) == fmt.Sprint(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// mutationPathSegment is one "."-separated step of a mutator's
+// spec.location, e.g. containers[name:*] becomes {field: "containers",
+// filterKey: "name", filterValue: "*"}.
+type mutationPathSegment struct {
+	field       string
+	filterKey   string
+	filterValue string
+}
+
+func parseMutationLocation(location string) []mutationPathSegment {
+	var segments []mutationPathSegment
+	for _, token := range strings.Split(location, ".") {
+		seg := mutationPathSegment{field: token}
+		if start := strings.Index(token, "["); start >= 0 && strings.HasSuffix(token, "]") {
+			seg.field = token[:start]
+			filter := token[start+1 : len(token)-1]
+			if kv := strings.SplitN(filter, ":", 2); len(kv) == 2 {
+				seg.filterKey, seg.filterValue = kv[0], kv[1]
+			}
+		}
+		segments = append(segments, seg)
+	}
+	return segments
+}
+
+// setAtLocation walks obj along segments and replaces whatever it finds at
+// the end with mutate(existing). A [field:value] segment fans out over every
+// matching element of a list field ("*" matches all).
+func setAtLocation(obj map[string]interface{}, segments []mutationPathSegment, mutate func(interface{}) (interface{}, bool)) error {
+	if len(segments) == 0 {
+		return fmt.Errorf("empty location")
+	}
+	seg := segments[0]
+	rest := segments[1:]
+
+	if len(rest) == 0 && seg.filterKey == "" {
+		updated, ok := mutate(obj[seg.field])
+		if ok {
+			obj[seg.field] = updated
+		}
+		return nil
+	}
+
+	if seg.filterKey == "" {
+		child, ok := obj[seg.field].(map[string]interface{})
+		if !ok {
+			child = map[string]interface{}{}
+			obj[seg.field] = child
+		}
+		return setAtLocation(child, rest, mutate)
+	}
+
+	list, _ := obj[seg.field].([]interface{})
+	for _, e := range list {
+		entry, ok := e.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if seg.filterValue != "*" && fmt.Sprint(entry[seg.filterKey]) != seg.filterValue {
+			continue
+		}
+		if len(rest) == 0 {
+			updated, ok := mutate(entry)
+			_ = ok
+			if m, ok := updated.(map[string]interface{}); ok {
+				for k, v := range m {
+					entry[k] = v
+				}
+			}
+			continue
+		}
+		if err := setAtLocation(entry, rest, mutate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// diffMutation returns dotted field paths present in actual or expected
+// with different values, skipping mutationIgnoredFields.
+func diffMutation(actual, expected map[string]interface{}, prefix string) []string {
+	var diffs []string
+	keys := map[string]bool{}
+	for k := range actual {
+		keys[k] = true
+	}
+	for k := range expected {
+		keys[k] = true
+	}
+
+	names := make([]string, 0, len(keys))
+	for k := range keys {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	for _, k := range names {
+		path := k
+		if prefix != "" {
+			path = prefix + "." + k
+		}
+		if mutationIgnoredFields[path] {
+			continue
+		}
+		av, aok := actual[k]
+		ev, eok := expected[k]
+		if aok != eok {
+			diffs = append(diffs, path)
+			continue
+		}
+		am, aIsMap := av.(map[string]interface{})
+		em, eIsMap := ev.(map[string]interface{})
+		if aIsMap && eIsMap {
+			diffs = append(diffs, diffMutation(am, em, path)...)
+			continue
+		}
+		if fmt.Sprint(av) != fmt.Sprint(ev) {
+			diffs = append(diffs, path)
+		}
+	}
+	return diffs
+}
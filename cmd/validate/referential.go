@@ -0,0 +1,187 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// inventoryRefPattern matches the bracketed data.inventory lookups
+// Gatekeeper's own referential-constraint templates use:
+// data.inventory.namespace[ns][apiVersion][kind] and
+// data.inventory.cluster[apiVersion][kind]. apiVersion is almost always a
+// bound Rego variable or a "_" wildcard rather than a literal (uniqueness
+// templates iterate the inventory rather than naming a version up front),
+// so only group 2 (kind) is required to be a quoted literal; group 1
+// (apiVersion) is captured but may be a variable name.
+var inventoryRefPattern = regexp.MustCompile(`data\.inventory\.(?:namespace\[[^\]]+\]|cluster)\[\s*"?([\w./-]+)"?\s*\]\[\s*"([\w]+)"\s*\]`)
+
+// wildcardGroup marks a required GVK whose Rego wildcards or variable-binds
+// apiVersion (the common case - see inventoryRefPattern) rather than naming
+// a literal group/version, so the real API group is unknown: it's a
+// "some group, this kind" requirement rather than a concrete GVK.
+const wildcardGroup = "*"
+
+// requiredInventoryGVKs scans a template's Rego for data.inventory.* lookups
+// and returns the distinct "group/kind" pairs it found, sorted for stable
+// output. group is wildcardGroup when the Rego didn't name a literal
+// apiVersion.
+func requiredInventoryGVKs(templateData []byte) []string {
+	var tmpl map[string]interface{}
+	if err := yaml.Unmarshal(templateData, &tmpl); err != nil {
+		return nil
+	}
+	spec, _ := tmpl["spec"].(map[string]interface{})
+	targets, _ := spec["targets"].([]interface{})
+
+	seen := map[string]bool{}
+	for _, t := range targets {
+		target, ok := t.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		rego, _ := target["rego"].(string)
+		for _, m := range inventoryRefPattern.FindAllStringSubmatch(rego, -1) {
+			seen[groupFromAPIVersion(rego, m[0], m[1])+"/"+m[2]] = true
+		}
+	}
+
+	gvks := make([]string, 0, len(seen))
+	for gvk := range seen {
+		gvks = append(gvks, gvk)
+	}
+	sort.Strings(gvks)
+	return gvks
+}
+
+// groupFromAPIVersion returns apiVersion's API group, or wildcardGroup if
+// apiVersion isn't a quoted literal in rego at match's position (a bound
+// variable or "_" wildcard, which carries no group information). Core
+// resources (a literal apiVersion with no "/", e.g. "v1") return "".
+func groupFromAPIVersion(rego, match, apiVersion string) string {
+	if !strings.Contains(match, `"`+apiVersion+`"`) {
+		return wildcardGroup
+	}
+	if i := strings.LastIndex(apiVersion, "/"); i >= 0 {
+		return apiVersion[:i]
+	}
+	return ""
+}
+
+// loadOrSynthesizeSyncConfig returns the policy's Config/SyncSet object,
+// parsed from syncPath if the library ships one, or else a minimal Config
+// synthesized from the GVKs the template's Rego requires.
+func loadOrSynthesizeSyncConfig(syncPath string, templateData []byte) (map[string]interface{}, error) {
+	if syncPath != "" {
+		data, err := os.ReadFile(syncPath)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", syncPath, err)
+		}
+		var config map[string]interface{}
+		if err := yaml.Unmarshal(data, &config); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", syncPath, err)
+		}
+		return config, nil
+	}
+
+	var syncOnly []interface{}
+	for _, gvk := range requiredInventoryGVKs(templateData) {
+		group, kind := splitGVK(gvk)
+		if group == wildcardGroup {
+			// Config's sync entries require a concrete group; fall back to
+			// core, the most common case, since the Rego itself didn't name one.
+			group = ""
+		}
+		syncOnly = append(syncOnly, map[string]interface{}{"group": group, "version": "v1", "kind": kind})
+	}
+	return map[string]interface{}{
+		"apiVersion": "config.gatekeeper.sh/v1alpha1",
+		"kind":       "Config",
+		"metadata":   map[string]interface{}{"name": "config", "namespace": "gatekeeper-system"},
+		"spec":       map[string]interface{}{"sync": map[string]interface{}{"syncOnly": syncOnly}},
+	}, nil
+}
+
+// missingSyncGVKs reports which of requiredGVKs (as "group/kind" pairs) the
+// sync config's spec.sync.syncOnly doesn't cover. A requirement with
+// wildcardGroup - the Rego didn't name a literal apiVersion - is covered by
+// any syncOnly entry for that kind, regardless of group.
+func missingSyncGVKs(requiredGVKs []string, syncConfig map[string]interface{}) []string {
+	covered := map[string]bool{}
+	coveredKinds := map[string]bool{}
+	spec, _ := syncConfig["spec"].(map[string]interface{})
+	sync, _ := spec["sync"].(map[string]interface{})
+	syncOnly, _ := sync["syncOnly"].([]interface{})
+	for _, e := range syncOnly {
+		entry, ok := e.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		group, _ := entry["group"].(string)
+		kind, _ := entry["kind"].(string)
+		covered[group+"/"+kind] = true
+		coveredKinds[kind] = true
+	}
+
+	var missing []string
+	for _, gvk := range requiredGVKs {
+		group, kind := splitGVK(gvk)
+		if covered[gvk] || (group == wildcardGroup && coveredKinds[kind]) {
+			continue
+		}
+		missing = append(missing, gvk)
+	}
+	return missing
+}
+
+// findReferentialData locates the cached cluster objects a task's artifacts
+// carry for referential constraints to look up - the same inventory-*.yaml
+// files the generator writes for cases sourced from gatekeeper-library's own
+// inventory fixtures.
+func findReferentialData(taskDir string) ([][]byte, error) {
+	matches, err := filepath.Glob(filepath.Join(taskDir, "artifacts", "inventory-*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(matches)
+
+	var data [][]byte
+	for _, p := range matches {
+		d, err := os.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", p, err)
+		}
+		data = append(data, d)
+	}
+	return data, nil
+}
+
+// splitGVK splits a "group/kind" pair back into its parts; group is empty
+// for core API resources.
+func splitGVK(gvk string) (group, kind string) {
+	for i := len(gvk) - 1; i >= 0; i-- {
+		if gvk[i] == '/' {
+			return gvk[:i], gvk[i+1:]
+		}
+	}
+	return "", gvk
+}
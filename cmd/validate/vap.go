@@ -0,0 +1,244 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// vapCluster holds the live API server used to evaluate
+// ValidatingAdmissionPolicy resources; unlike gator test, CEL validations
+// only run inside a real apiserver, so VAP mode needs one.
+type vapCluster struct {
+	kubeconfig string
+}
+
+// connectVAPCluster verifies kubeconfig (or $KUBECONFIG if unset) points at
+// a reachable cluster. It doesn't create one - VAP mode is expected to be
+// pointed at a cluster the caller already has running (e.g. the kind
+// cluster the generator's -verify pass uses).
+func connectVAPCluster(kubeconfig string) (*vapCluster, error) {
+	if kubeconfig == "" {
+		kubeconfig = os.Getenv("KUBECONFIG")
+	}
+	if kubeconfig == "" {
+		return nil, fmt.Errorf("no kubeconfig provided (use -kubeconfig or $KUBECONFIG)")
+	}
+	if err := exec.Command("kubectl", "--kubeconfig", kubeconfig, "cluster-info").Run(); err != nil {
+		return nil, fmt.Errorf("cluster unreachable via %s: %w", kubeconfig, err)
+	}
+	return &vapCluster{kubeconfig: kubeconfig}, nil
+}
+
+func (vc *vapCluster) apply(obj map[string]interface{}) error {
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command("kubectl", "--kubeconfig", vc.kubeconfig, "apply", "-f", "-")
+	cmd.Stdin = bytes.NewReader(data)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w", strings.TrimSpace(string(out)), err)
+	}
+	return nil
+}
+
+func (vc *vapCluster) cleanup(objs ...map[string]interface{}) {
+	for _, obj := range objs {
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			continue
+		}
+		cmd := exec.Command("kubectl", "--kubeconfig", vc.kubeconfig, "delete", "--ignore-not-found", "-f", "-")
+		cmd.Stdin = bytes.NewReader(data)
+		_ = cmd.Run()
+	}
+}
+
+// dryRunDeny server-dry-run-applies each document in data and returns one
+// violation message per document the API server denied - which is how a
+// ValidatingAdmissionPolicy's CEL validations actually get enforced.
+func (vc *vapCluster) dryRunDeny(data []byte) []string {
+	var violations []string
+	for _, doc := range bytes.Split(data, []byte("\n---")) {
+		doc = bytes.TrimSpace(doc)
+		if len(doc) == 0 {
+			continue
+		}
+		cmd := exec.Command("kubectl", "--kubeconfig", vc.kubeconfig, "apply", "--dry-run=server", "-f", "-")
+		cmd.Stdin = bytes.NewReader(doc)
+		out, err := cmd.CombinedOutput()
+		if err != nil && strings.Contains(strings.ToLower(string(out)), "denied") {
+			violations = append(violations, strings.TrimSpace(string(out)))
+		}
+	}
+	return violations
+}
+
+// runVAPTest applies a ValidatingAdmissionPolicy + Binding for taskName -
+// read from vapPath if the library ships one, otherwise synthesized from
+// the ConstraintTemplate's CEL validations block - then dry-run applies the
+// alpha/beta resources and returns the violations the API server reported
+// for each.
+func runVAPTest(vap *vapCluster, taskName, templatePath, vapPath string, alphaData, betaData []byte) ([]string, []string, error) {
+	if vap == nil {
+		return nil, nil, fmt.Errorf("no VAP cluster configured")
+	}
+
+	var policy, binding map[string]interface{}
+	if vapPath != "" {
+		data, err := os.ReadFile(vapPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading %s: %w", vapPath, err)
+		}
+		policy, binding, err = parseVAPFile(taskName, data)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		templateData, err := os.ReadFile(templatePath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading template: %w", err)
+		}
+		var ok bool
+		policy, binding, ok = synthesizeVAP(taskName, templateData)
+		if !ok {
+			return nil, nil, fmt.Errorf("template has no CEL validations block and no vap.yaml sibling was found")
+		}
+	}
+
+	if err := vap.apply(policy); err != nil {
+		return nil, nil, fmt.Errorf("applying ValidatingAdmissionPolicy: %w", err)
+	}
+	if err := vap.apply(binding); err != nil {
+		vap.cleanup(policy)
+		return nil, nil, fmt.Errorf("applying ValidatingAdmissionPolicyBinding: %w", err)
+	}
+	defer vap.cleanup(policy, binding)
+
+	alphaViolations := vap.dryRunDeny(alphaData)
+	betaViolations := vap.dryRunDeny(betaData)
+	return alphaViolations, betaViolations, nil
+}
+
+// parseVAPFile splits a hand-written vap.yaml into its
+// ValidatingAdmissionPolicy and ValidatingAdmissionPolicyBinding documents.
+// If the file only carries the policy, a binding that denies everything the
+// policy matches is synthesized to go with it.
+func parseVAPFile(taskName string, data []byte) (policy, binding map[string]interface{}, err error) {
+	for _, doc := range bytes.Split(data, []byte("\n---")) {
+		doc = bytes.TrimSpace(doc)
+		if len(doc) == 0 {
+			continue
+		}
+		var obj map[string]interface{}
+		if err := yaml.Unmarshal(doc, &obj); err != nil {
+			return nil, nil, fmt.Errorf("parsing vap.yaml: %w", err)
+		}
+		switch obj["kind"] {
+		case "ValidatingAdmissionPolicy":
+			policy = obj
+		case "ValidatingAdmissionPolicyBinding":
+			binding = obj
+		}
+	}
+	if policy == nil {
+		return nil, nil, fmt.Errorf("vap.yaml has no ValidatingAdmissionPolicy document")
+	}
+	if binding == nil {
+		binding = bindingFor(taskName, policyName(policy))
+	}
+	return policy, binding, nil
+}
+
+// synthesizeVAP builds a ValidatingAdmissionPolicy + Binding pair from a
+// ConstraintTemplate's CEL validations block, the way Gatekeeper's own
+// template-to-VAP generation does: one matchConstraints rule covering any
+// resource, carrying the template's validations verbatim.
+func synthesizeVAP(taskName string, templateData []byte) (policy, binding map[string]interface{}, ok bool) {
+	var tmpl map[string]interface{}
+	if err := yaml.Unmarshal(templateData, &tmpl); err != nil {
+		return nil, nil, false
+	}
+	validations := templateCELValidations(tmpl)
+	if len(validations) == 0 {
+		return nil, nil, false
+	}
+
+	name := "k8s-ai-bench-" + taskName
+	policy = map[string]interface{}{
+		"apiVersion": "admissionregistration.k8s.io/v1",
+		"kind":       "ValidatingAdmissionPolicy",
+		"metadata":   map[string]interface{}{"name": name},
+		"spec": map[string]interface{}{
+			"failurePolicy": "Fail",
+			"matchConstraints": map[string]interface{}{
+				"resourceRules": []interface{}{
+					map[string]interface{}{
+						"apiGroups":   []interface{}{"*"},
+						"apiVersions": []interface{}{"*"},
+						"operations":  []interface{}{"CREATE", "UPDATE"},
+						"resources":   []interface{}{"*"},
+					},
+				},
+			},
+			"validations": validations,
+		},
+	}
+	return policy, bindingFor(taskName, name), true
+}
+
+// templateCELValidations returns the entries of the CEL validations block in
+// tmpl's spec.targets (empty when there is none), the marker Gatekeeper uses
+// to decide a template has a native VAP equivalent.
+func templateCELValidations(tmpl map[string]interface{}) []interface{} {
+	spec, _ := tmpl["spec"].(map[string]interface{})
+	targets, _ := spec["targets"].([]interface{})
+	var validations []interface{}
+	for _, t := range targets {
+		target, ok := t.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if v, ok := target["validations"].([]interface{}); ok {
+			validations = append(validations, v...)
+		}
+	}
+	return validations
+}
+
+func policyName(policy map[string]interface{}) string {
+	meta, _ := policy["metadata"].(map[string]interface{})
+	name, _ := meta["name"].(string)
+	return name
+}
+
+func bindingFor(taskName, policyName string) map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": "admissionregistration.k8s.io/v1",
+		"kind":       "ValidatingAdmissionPolicyBinding",
+		"metadata":   map[string]interface{}{"name": "k8s-ai-bench-" + taskName + "-binding"},
+		"spec": map[string]interface{}{
+			"policyName":        policyName,
+			"validationActions": []interface{}{"Deny"},
+		},
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/open-policy-agent/gatekeeper/v3/pkg/gator"
+	gktest "github.com/open-policy-agent/gatekeeper/v3/pkg/gator/test"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/yaml"
+)
+
+// admissionVerdict is the outcome of running one object through an
+// in-process evaluation of a template/constraint pair - the repair loop's
+// stand-in for what a live Gatekeeper admission webhook would decide.
+type admissionVerdict struct {
+	Denied   bool
+	Messages []string
+}
+
+// checkAdmission evaluates objectYAML against templateYAML/constraintYAML
+// using gator's own gktest.Runner - the same façade cmd/validate's
+// runGatorSuite is built on - so the repair loop gets a real admission
+// verdict instead of trusting whatever the LLM's diff claims to do.
+func checkAdmission(templateYAML, constraintYAML, objectYAML []byte) (admissionVerdict, error) {
+	fs := filesys.MakeFsInMemory()
+	for name, data := range map[string][]byte{
+		"template.yaml":   templateYAML,
+		"constraint.yaml": constraintYAML,
+		"object.yaml":     objectYAML,
+	} {
+		if err := fs.WriteFile(name, data); err != nil {
+			return admissionVerdict{}, fmt.Errorf("writing %s: %w", name, err)
+		}
+	}
+
+	suiteData, err := yaml.Marshal(map[string]interface{}{
+		"kind":       "Suite",
+		"apiVersion": "test.gatekeeper.sh/v1alpha1",
+		"metadata":   map[string]interface{}{"name": "repair-check"},
+		"tests": []map[string]interface{}{{
+			"name":       "repair-check",
+			"template":   "template.yaml",
+			"constraint": "constraint.yaml",
+			"cases": []map[string]interface{}{
+				{"name": "object", "object": "object.yaml", "assertions": []map[string]interface{}{{"violations": "yes"}}},
+			},
+		}},
+	})
+	if err != nil {
+		return admissionVerdict{}, err
+	}
+	if err := fs.WriteFile("suite.yaml", suiteData); err != nil {
+		return admissionVerdict{}, err
+	}
+
+	runner := gktest.Runner{FS: fs, NewClient: gator.NewOPAClient}
+	suiteResult := runner.Run(context.Background(), gktest.Filter{}, "suite.yaml")
+	if suiteResult.Error != nil {
+		return admissionVerdict{}, suiteResult.Error
+	}
+
+	// The case asserts "violations: yes". A nil CaseResult error means the
+	// assertion held, i.e. the constraint denied the object; a non-nil
+	// error means it didn't, and carries gator's explanation why.
+	verdict := admissionVerdict{Denied: true}
+	for _, tr := range suiteResult.TestResults {
+		for _, cr := range tr.CaseResults {
+			if cr.Error != nil {
+				verdict.Denied = false
+				verdict.Messages = append(verdict.Messages, cr.Error.Error())
+			}
+		}
+	}
+	return verdict, nil
+}
@@ -0,0 +1,37 @@
+package main
+
+// AdmissionReviewAdapter turns an AdmissionReview fixture — the format most
+// gatekeeper-library suite cases ship their inputs in — into a plain
+// manifest for the deploy path plus the raw AdmissionRequest fields an
+// evaluator needs to call Gatekeeper's validating webhook directly.
+type AdmissionReviewAdapter struct{}
+
+func NewAdmissionReviewAdapter() *AdmissionReviewAdapter { return &AdmissionReviewAdapter{} }
+
+// Adapt extracts request.object (and, when present, request.oldObject,
+// request.userInfo and request.operation) from doc. object is the plain
+// manifest to deploy; request is the AdmissionRequest payload to emit
+// alongside it. ok is false if doc has no usable request.object, in which
+// case the case should be dropped the same way it was before.
+func (AdmissionReviewAdapter) Adapt(doc map[string]interface{}) (object map[string]interface{}, request map[string]interface{}, ok bool) {
+	req, _ := doc["request"].(map[string]interface{})
+	if req == nil {
+		return nil, nil, false
+	}
+	object, ok = req["object"].(map[string]interface{})
+	if !ok {
+		return nil, nil, false
+	}
+
+	request = map[string]interface{}{"object": object}
+	if oldObject, ok := req["oldObject"].(map[string]interface{}); ok {
+		request["oldObject"] = oldObject
+	}
+	if userInfo, ok := req["userInfo"].(map[string]interface{}); ok {
+		request["userInfo"] = userInfo
+	}
+	if operation, ok := req["operation"].(string); ok {
+		request["operation"] = operation
+	}
+	return object, request, true
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"sigs.k8s.io/yaml"
+)
+
+// ConstraintScoper rewrites a Gatekeeper constraint's spec.match block so it
+// targets an isolated task namespace, replacing the previous
+// strings.Replace(constraintYAML, `- "default"`, ...) approach that silently
+// no-ops on constraints using namespaceSelector, excludedNamespaces, or
+// differently quoted/indented namespace lists.
+type ConstraintScoper struct{}
+
+// NewConstraintScoper builds a ConstraintScoper. It holds no state today but
+// exists as an extension point (e.g. for org-specific scoping policy).
+func NewConstraintScoper() *ConstraintScoper {
+	return &ConstraintScoper{}
+}
+
+// labelSelector mirrors the subset of metav1.LabelSelector we need to walk.
+type labelSelector struct {
+	MatchLabels      map[string]string        `json:"matchLabels,omitempty"`
+	MatchExpressions []map[string]interface{} `json:"matchExpressions,omitempty"`
+}
+
+// constraintMatch mirrors constraint.tmpl.spec.match from the Gatekeeper
+// constraint template API.
+type constraintMatch struct {
+	Kinds              []map[string]interface{} `json:"kinds,omitempty"`
+	Scope              string                   `json:"scope,omitempty"`
+	Namespaces         []string                 `json:"namespaces,omitempty"`
+	ExcludedNamespaces []string                 `json:"excludedNamespaces,omitempty"`
+	LabelSelector      *labelSelector           `json:"labelSelector,omitempty"`
+	NamespaceSelector  *labelSelector           `json:"namespaceSelector,omitempty"`
+}
+
+// Scope rewrites constraintYAML so its spec.match targets ns, and re-emits
+// deterministic YAML. It leaves every other field (parameters,
+// enforcementAction, metadata, ...) untouched.
+func (s *ConstraintScoper) Scope(constraintYAML []byte, ns string) ([]byte, error) {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(constraintYAML, &doc); err != nil {
+		return nil, fmt.Errorf("parsing constraint: %w", err)
+	}
+
+	spec, ok := doc["spec"].(map[string]interface{})
+	if !ok {
+		// No spec.match to scope (malformed constraint); leave as-is.
+		return yaml.Marshal(doc)
+	}
+
+	match, err := decodeMatch(spec["match"])
+	if err != nil {
+		return nil, fmt.Errorf("parsing spec.match: %w", err)
+	}
+
+	scopeMatchToNamespace(match, ns)
+
+	matchOut, err := encodeMatch(match)
+	if err != nil {
+		return nil, fmt.Errorf("re-encoding spec.match: %w", err)
+	}
+	if matchOut == nil {
+		delete(spec, "match")
+	} else {
+		spec["match"] = matchOut
+	}
+
+	return yaml.Marshal(doc)
+}
+
+func decodeMatch(raw interface{}) (*constraintMatch, error) {
+	match := &constraintMatch{}
+	if raw == nil {
+		return match, nil
+	}
+	data, err := yaml.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, match); err != nil {
+		return nil, err
+	}
+	return match, nil
+}
+
+func encodeMatch(match *constraintMatch) (map[string]interface{}, error) {
+	data, err := yaml.Marshal(match)
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]interface{}
+	if err := yaml.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return out, nil
+}
+
+// scopeMatchToNamespace mutates match so it applies to ns: it drops any
+// excludedNamespaces entry that would exclude ns, rewrites an explicit
+// namespace list (replacing "default" or appending ns), and, when match
+// already has a namespaceSelector, sets its matchLabels to target ns's
+// auto-populated "kubernetes.io/metadata.name" label (a match with no
+// namespaceSelector does not get one). namespaceSelector and namespaces are
+// independent match fields (Gatekeeper ANDs them together when both are
+// set), so both are retargeted rather than treating one as overriding the
+// other - otherwise a constraint with both set would still require
+// membership in the original namespace list to ever match.
+func scopeMatchToNamespace(match *constraintMatch, ns string) {
+	match.ExcludedNamespaces = dropNamespace(match.ExcludedNamespaces, ns)
+
+	if match.NamespaceSelector != nil {
+		if match.NamespaceSelector.MatchLabels == nil {
+			match.NamespaceSelector.MatchLabels = map[string]string{}
+		}
+		match.NamespaceSelector.MatchLabels["kubernetes.io/metadata.name"] = ns
+	}
+
+	switch {
+	case len(match.Namespaces) > 0:
+		match.Namespaces = retargetNamespaces(match.Namespaces, ns)
+	case match.NamespaceSelector == nil:
+		// No namespace scoping at all means the constraint applies
+		// cluster-wide; scope it down to our isolated namespace so it
+		// doesn't interfere with (or get interfered with by) other tasks
+		// sharing the cluster.
+		match.Namespaces = []string{ns}
+	}
+}
+
+func dropNamespace(namespaces []string, ns string) []string {
+	if len(namespaces) == 0 {
+		return namespaces
+	}
+	kept := namespaces[:0]
+	for _, n := range namespaces {
+		if n != ns {
+			kept = append(kept, n)
+		}
+	}
+	return kept
+}
+
+func retargetNamespaces(namespaces []string, ns string) []string {
+	out := make([]string, 0, len(namespaces)+1)
+	found := false
+	for _, n := range namespaces {
+		if n == "default" {
+			out = append(out, ns)
+			found = true
+			continue
+		}
+		if n == ns {
+			found = true
+		}
+		out = append(out, n)
+	}
+	if !found {
+		out = append(out, ns)
+	}
+	return out
+}
+
+// rewriteConstraint reads the constraint at srcPath, scopes it to ns via
+// ConstraintScoper, and writes the result to dstPath. This is the on-disk
+// constraint the generated task's runner actually applies, so it must go
+// through the same scoping as PromptContext.ConstraintYAML.
+func rewriteConstraint(srcPath, dstPath, ns string) error {
+	data, err := os.ReadFile(srcPath)
+	if err != nil {
+		return err
+	}
+	scoped, err := NewConstraintScoper().Scope(data, ns)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dstPath, scoped, 0644)
+}
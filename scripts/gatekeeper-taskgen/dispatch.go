@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// taskOutput is one generateTask run's outcome, passed back from a worker
+// to dispatchTasks's collector over a channel so it can be flushed in
+// sortedKeys(taskMap) order regardless of which worker finished first.
+type taskOutput struct {
+	index        int
+	id           string
+	log          string
+	skipped      bool
+	generated    bool
+	err          error
+	repairResult *RepairResult
+	verifyResult *VerifyResult
+	decision     SelectionDecision
+}
+
+// dispatchTasks runs generateTask for every task in taskMap across
+// cfg.Jobs workers, preserving run()'s original sequential output: each
+// task's log lines are buffered by the worker that produced them and
+// flushed by a single collector goroutine in sortedKeys order, so
+// concurrent workers never interleave a line mid-task the way unbuffered
+// fmt.Printf from multiple goroutines would.
+func dispatchTasks(cfg Config, vc *verifyCluster, taskMap map[string]TaskMetadata) (generated, skipped int, repairResults []RepairResult, verifyResults []VerifyResult, decisions []SelectionDecision) {
+	ids := sortedKeys(taskMap)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	jobs := cfg.Jobs
+	if jobs <= 0 {
+		jobs = 1
+	}
+
+	indices := make(chan int)
+	go func() {
+		defer close(indices)
+		for i := range ids {
+			select {
+			case indices <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	outputs := make(chan taskOutput)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				id := ids[i]
+				outputs <- processTask(ctx, cfg, vc, i, id, taskMap[id])
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(outputs)
+	}()
+
+	// pending holds completed tasks whose turn hasn't come up yet; flush
+	// flushes one, advancing next, bookkeeping generated/skipped/reports,
+	// and triggering -fail-fast on the first real error.
+	pending := map[int]taskOutput{}
+	next := 0
+	flush := func(out taskOutput) {
+		fmt.Print(out.log)
+		if out.repairResult != nil {
+			repairResults = append(repairResults, *out.repairResult)
+		}
+		if out.verifyResult != nil {
+			verifyResults = append(verifyResults, *out.verifyResult)
+		}
+		if out.skipped {
+			skipped++
+		}
+		if out.generated {
+			generated++
+		}
+		if out.decision.TaskID != "" {
+			decisions = append(decisions, out.decision)
+		}
+		if out.err != nil && cfg.FailFast {
+			cancel()
+		}
+	}
+
+	for out := range outputs {
+		pending[out.index] = out
+		for {
+			o, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			flush(o)
+		}
+	}
+
+	// The feeder dispatches indices in order, even when -fail-fast stops it
+	// early, and every dispatched task sends back a result, so pending is
+	// normally empty here. Flush whatever's left in order rather than
+	// dropping it.
+	remaining := make([]int, 0, len(pending))
+	for i := range pending {
+		remaining = append(remaining, i)
+	}
+	sort.Ints(remaining)
+	for _, i := range remaining {
+		flush(pending[i])
+	}
+
+	return generated, skipped, repairResults, verifyResults, decisions
+}
+
+// processTask runs one task through shouldSkip and generateTask, buffering
+// every cfg.Log line it produces into its own taskOutput instead of
+// printing directly, so dispatchTasks's collector can flush it in order.
+func processTask(ctx context.Context, cfg Config, vc *verifyCluster, index int, id string, task TaskMetadata) taskOutput {
+	out := taskOutput{index: index, id: id}
+	var logBuf strings.Builder
+	defer func() { out.log = logBuf.String() }()
+	cfg.Log = func(format string, args ...interface{}) { fmt.Fprintf(&logBuf, format, args...) }
+
+	if ctx.Err() != nil {
+		out.skipped = true
+		fmt.Fprintf(&logBuf, "Skipped %s: cancelled (-fail-fast)\n", id)
+		return out
+	}
+
+	out.decision = shouldSkip(cfg, task)
+	if out.decision.Skip {
+		fmt.Fprintf(&logBuf, "Skipped %s: %s\n", id, out.decision.Reason)
+		out.skipped = true
+		return out
+	}
+
+	repairResult, verifyResult, err := generateTask(ctx, cfg, vc, task)
+	if err != nil {
+		fmt.Fprintf(&logBuf, "Skipped %s: %v\n", id, err)
+		out.skipped = true
+		out.err = err
+		// Still collect the repair result for the report even if it errored
+		out.repairResult = repairResult
+		return out
+	}
+	out.repairResult = repairResult
+	if verifyResult != nil {
+		out.verifyResult = verifyResult
+		if !verifyResult.Passed {
+			fmt.Fprintf(&logBuf, "Dropping task %s: verification failed\n", id)
+			os.RemoveAll(filepath.Join(cfg.OutputDir, task.TaskID))
+			out.skipped = true
+			return out
+		}
+	}
+	if cfg.Verbose {
+		fmt.Fprintf(&logBuf, "Generated task %s\n", id)
+	}
+	out.generated = true
+	return out
+}
@@ -0,0 +1,50 @@
+package main
+
+import "context"
+
+// aiLimiter caps how many AI calls (prompt generation or repair) are in
+// flight at once, independent of -jobs, so a wide -jobs worker pool doesn't
+// blow through the configured provider's rate limit. It's a plain
+// channel-backed semaphore rather than a real token bucket: it bounds
+// concurrency, not requests/sec, which is the dimension Gemini/OpenAI/
+// Anthropic actually rate-limit on for this generator's call pattern (one
+// request in flight per token, not bursts).
+type aiLimiter struct {
+	tokens chan struct{}
+}
+
+// newAILimiter returns a limiter allowing n concurrent AI calls. n <= 0 is
+// treated as 1, since a limiter with no tokens would deadlock every caller.
+func newAILimiter(n int) *aiLimiter {
+	if n <= 0 {
+		n = 1
+	}
+	l := &aiLimiter{tokens: make(chan struct{}, n)}
+	for i := 0; i < n; i++ {
+		l.tokens <- struct{}{}
+	}
+	return l
+}
+
+// acquire blocks until a token is free or ctx is cancelled. A nil receiver
+// (an unconfigured limiter) is treated as unlimited.
+func (l *aiLimiter) acquire(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	select {
+	case <-l.tokens:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release returns a token acquired via acquire. It's a no-op on a nil
+// receiver, matching acquire.
+func (l *aiLimiter) release() {
+	if l == nil {
+		return
+	}
+	l.tokens <- struct{}{}
+}
@@ -6,68 +6,75 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strings"
+	"time"
 
-	"google.golang.org/genai"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/tools/clientcmd"
 	"sigs.k8s.io/yaml"
 )
 
-var defaultSkipList = []string{
-	// Name-sensitive or deprecated policies
-	"block-endpoint-default-role",
-	"noupdateserviceaccount",
-	"verifydeprecatedapi",
-	// Tasks with non-deployable resources (fake images, deprecated registries)
-	// These can't be fixed without breaking alpha/beta distinction
-	"allowed-reposv2",
-	"disallowed-tags",
-	"repo-must-not-be-k8s-gcr-io",
-	// Tasks with high resource requests that won't schedule on small clusters
-	// Capping resources would make both alpha and beta pass
-	"container-cpu-requests-memory-limits-and-requests",
-	"container-limits",
-	"container-limits-and-requests",
-	"container-limits-ignore-cpu",
-	"container-requests",
-	"ephemeral-storage-limit",
-	"memory-and-cpu-ratios",
-	"memory-ratio-only",
-	// Tasks with PVC issues
-	"storageclass",
-	"storageclass-allowlist",
-	// Tasks with complex runtime issues that need manual fixes
-	"container-image-must-have-digest", // OPA init container
-	"required-probes",                  // readiness probe port mismatches
-}
-
 func main() {
 	cfg := Config{}
+	var kubeconfig, apiResourcesCache, configPath string
+	var skipFlags []string
 	flag.StringVar(&cfg.LibraryRoot, "library-root", ".gatekeeper-library/library/general", "Path to gatekeeper-library general directory")
 	flag.StringVar(&cfg.OutputDir, "output-dir", "tasks/gatekeeper", "Directory to write tasks")
-	flag.Var(&stringSliceFlag{&cfg.SkipList}, "skip", "Patterns to skip (can be repeated)")
+	flag.StringVar(&configPath, "config", "", "Path to a SelectionConfig YAML file (skip rules, only list, per-task overrides); defaults to the generator's built-in list")
+	flag.Var(&stringSliceFlag{&skipFlags}, "skip", "Additional patterns to skip on top of -config (can be repeated)")
 	flag.BoolVar(&cfg.Verbose, "verbose", false, "Enable verbose logging")
-	flag.BoolVar(&cfg.Repair, "repair", false, "Repair beta manifests via Gemini after generation")
+	flag.BoolVar(&cfg.Repair, "repair", false, "Repair beta manifests via an LLM after generation")
+	flag.StringVar(&cfg.RepairBackend, "repair-backend", os.Getenv("REPAIR_BACKEND"), "LLM backend for -repair: gemini, openai, anthropic, ollama, or fake (default gemini)")
+	flag.BoolVar(&cfg.Verify, "verify", false, "Apply generated tasks to a live kind cluster and drop ones that don't deploy/enforce as claimed")
+	flag.BoolVar(&cfg.Ensemble, "ensemble", false, "Generate a candidate prompt from every configured provider and keep the highest-scoring one")
+	flag.StringVar(&cfg.AIBackend, "ai-backend", os.Getenv("AI_BACKEND"), "Restrict prompt generation to one backend: gemini, openai, anthropic, or fake (default: enable every backend with an API key set)")
+	flag.IntVar(&cfg.MaxRepairAttempts, "max-repair-attempts", 3, "Repair attempts per task before giving up, each re-checked against a real admission verdict")
+	flag.StringVar(&kubeconfig, "kubeconfig", "", "Kubeconfig used to resolve resource scope (namespaced vs cluster-scoped) via live discovery")
+	flag.StringVar(&apiResourcesCache, "api-resources-cache", "", "Path to a cached api-resources.json snapshot, used when -kubeconfig is unset or unreachable")
+	flag.DurationVar(&cfg.TaskTimeout, "task-timeout", 5*time.Minute, "Timeout written into each generated task.yaml (Go duration syntax, e.g. 90s, 2m30s, 10m)")
+	flag.DurationVar(&cfg.ApplyTimeout, "apply-timeout", 2*time.Minute, "Readiness deadline for setup.sh's task-apply call (shared by every object it waits on) and per-object timeout for -verify's waitForReady")
+	flag.DurationVar(&cfg.RepairTimeout, "repair-timeout", 10*time.Minute, "Timeout bounding repairTask's whole verification-guided repair loop")
+	flag.DurationVar(&cfg.NamespaceReadyTimeout, "namespace-ready-timeout", 2*time.Minute, "Timeout for the generated setup.sh's wait for the task namespace to become Active")
+	flag.DurationVar(&cfg.CleanupTimeout, "cleanup-timeout", 2*time.Minute, "Grace deadline for the generated cleanup.sh's task-cleanup call to wait for evicted pods to disappear")
+	flag.IntVar(&cfg.Jobs, "jobs", runtime.NumCPU(), "Number of tasks to generate concurrently")
+	flag.IntVar(&cfg.AIConcurrency, "ai-concurrency", 4, "Maximum AI calls (prompt generation + repair) in flight at once, across every -jobs worker, to respect provider rate limits")
+	flag.BoolVar(&cfg.FailFast, "fail-fast", false, "Cancel remaining task generation on the first generateTask error instead of continuing through the whole library")
 	flag.Parse()
 
-	cfg.SkipList = append(cfg.SkipList, defaultSkipList...)
+	if cfg.Repair && cfg.RepairTimeout < cfg.ApplyTimeout {
+		fmt.Fprintf(os.Stderr, "-repair-timeout (%s) must be >= -apply-timeout (%s): the repair loop needs to observe a failing apply before it can propose a fix\n", cfg.RepairTimeout, cfg.ApplyTimeout)
+		os.Exit(1)
+	}
 
-	// Initialize Gemini client if API key is available
-	if apiKey := os.Getenv("GEMINI_API_KEY"); apiKey != "" {
-		ctx := context.Background()
-		client, err := genai.NewClient(ctx, &genai.ClientConfig{
-			APIKey:  apiKey,
-			Backend: genai.BackendGeminiAPI,
-		})
+	selectionConfig, err := loadSelectionConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "-config: %v\n", err)
+		os.Exit(1)
+	}
+	for _, p := range skipFlags {
+		selectionConfig.Skip = append(selectionConfig.Skip, SkipRule{Pattern: p, Reason: "-skip flag"})
+	}
+	cfg.SelectionConfig = selectionConfig
+	cfg.ScopeResolver = NewScopeResolver(buildDiscoveryClient(kubeconfig), apiResourcesCache)
+	cfg.PromptGenerators = buildPromptGenerators(&cfg)
+	cfg.AILimiter = newAILimiter(cfg.AIConcurrency)
+	cfg.Log = func(format string, args ...interface{}) { fmt.Printf(format, args...) }
+
+	if len(cfg.PromptGenerators) == 0 {
+		fmt.Fprintln(os.Stderr, "No prompt-generation API key set (GEMINI_API_KEY, OPENAI_API_KEY, ANTHROPIC_API_KEY) - at least one is required for prompt generation")
+		os.Exit(1)
+	}
+
+	if cfg.Repair {
+		repairLLM, err := buildRepairLLM(&cfg)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: Failed to initialize Gemini client: %v\n", err)
-		} else {
-			cfg.GeminiClient = client
-			fmt.Println("Gemini client initialized - will generate prompts using AI")
+			fmt.Fprintf(os.Stderr, "-repair: %v\n", err)
+			os.Exit(1)
 		}
-	} else {
-		fmt.Fprintln(os.Stderr, "GEMINI_API_KEY not set - Gemini is required for prompt generation")
-		os.Exit(1)
+		cfg.RepairLLM = repairLLM
+		fmt.Printf("Repair backend enabled (%s)\n", repairLLM.Name())
 	}
 
 	if err := run(cfg); err != nil {
@@ -87,35 +94,23 @@ func run(cfg Config) error {
 
 	os.MkdirAll(cfg.OutputDir, 0755)
 
-	var generated, skipped int
-	var repairResults []RepairResult
-	for _, id := range sortedKeys(taskMap) {
-		task := taskMap[id]
-		if skip, reason := shouldSkip(cfg, task); skip {
-			fmt.Printf("Skipped %s: %s\n", id, reason)
-			skipped++
-			continue
-		}
-		repairResult, err := generateTask(cfg, task)
+	var vc *verifyCluster
+	if cfg.Verify {
+		var err error
+		vc, err = setupVerifyCluster()
 		if err != nil {
-			fmt.Printf("Skipped %s: %v\n", id, err)
-			skipped++
-			// Still collect the repair result for the report even if it errored
-			if repairResult != nil {
-				repairResults = append(repairResults, *repairResult)
-			}
-		} else {
-			if cfg.Verbose {
-				fmt.Printf("Generated task %s\n", id)
-			}
-			generated++
-			if repairResult != nil {
-				repairResults = append(repairResults, *repairResult)
-			}
+			return fmt.Errorf("setting up verify cluster: %w", err)
 		}
+		defer vc.Teardown()
 	}
+
+	generated, skipped, repairResults, verifyResults, decisions := dispatchTasks(cfg, vc, taskMap)
 	fmt.Printf("Generated tasks: %d (skipped %d)\n", generated, skipped)
 
+	if err := writeSelectionReport(cfg.OutputDir, decisions); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to write selection report: %v\n", err)
+	}
+
 	// Write repair report if repairs were attempted
 	if cfg.Repair && len(repairResults) > 0 {
 		if err := writeRepairReport(cfg.OutputDir, repairResults); err != nil {
@@ -125,44 +120,40 @@ func run(cfg Config) error {
 		}
 	}
 
-	return nil
-}
-
-func shouldSkip(cfg Config, task TaskMetadata) (bool, string) {
-	for _, skip := range cfg.SkipList {
-		if skip == task.TestName || skip == task.SuiteName || strings.Contains(task.TestName, skip) {
-			return true, "skip list"
-		}
-	}
-	alpha, beta := 0, 0
-	for _, c := range task.Cases {
-		if c.Expected == "alpha" {
-			alpha++
+	// Write verification report if verification was attempted, covering
+	// every task we tried (including the ones we just dropped above).
+	if cfg.Verify && len(verifyResults) > 0 {
+		if err := writeVerificationReport(cfg.OutputDir, verifyResults); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to write verification report: %v\n", err)
 		} else {
-			beta++
+			fmt.Printf("Verification report written to %s/verification.json\n", cfg.OutputDir)
 		}
 	}
-	if alpha == 0 || beta == 0 {
-		return true, fmt.Sprintf("missing alpha or beta cases (alpha=%d beta=%d)", alpha, beta)
-	}
-	return false, ""
+
+	return nil
 }
 
-func generateTask(cfg Config, task TaskMetadata) (*RepairResult, error) {
+func generateTask(ctx context.Context, cfg Config, vc *verifyCluster, task TaskMetadata) (*RepairResult, *VerifyResult, error) {
 	outDir := filepath.Join(cfg.OutputDir, task.TaskID)
+	taskTimeout, ns, extraInventoryFiles := resolveTaskOverride(cfg, task.TaskID)
 
 	// Generate manifests and collect prompt context
-	artifacts, promptCtx, err := GenerateManifests(task, outDir)
+	artifacts, promptCtx, err := GenerateManifests(task, outDir, cfg, ns)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	// Generate prompt
-	prompt, err := BuildPrompt(cfg, promptCtx)
-	if err != nil {
-		return nil, err
+	if extraInventoryFiles != nil {
+		rel, err := copyExtraInventory(outDir, extraInventoryFiles)
+		if err != nil {
+			return nil, nil, err
+		}
+		artifacts.ExtraInventory = rel
 	}
 
+	// Generate prompt
+	prompt := BuildPrompt(ctx, cfg, promptCtx)
+
 	// Write task.yaml
 	taskYAML := fmt.Sprintf(`script:
 - prompt: |
@@ -173,29 +164,36 @@ expect:
 - contains: "VIOLATING: resource-beta-\\d+"
 - notContains: "VIOLATING: resource-alpha-\\d+"
 isolation: cluster
-timeout: 5m
-`, indent(prompt, "    "))
+timeout: %s
+`, indent(prompt, "    "), taskTimeout)
 	os.WriteFile(filepath.Join(outDir, "task.yaml"), []byte(taskYAML), 0644)
 
 	// Write suite.yaml
 	writeSuite(outDir, task, artifacts)
 
 	// Rewrite constraint
-	rewriteConstraint(task.ConstraintPath, filepath.Join(outDir, "constraint.yaml"), "gk-"+task.TaskID)
+	rewriteConstraint(task.ConstraintPath, filepath.Join(outDir, "constraint.yaml"), ns)
 	copyFile(task.TemplatePath, filepath.Join(outDir, "template.yaml"))
 
 	// Write setup/cleanup scripts
-	writeScripts(outDir, task.TaskID, artifacts)
+	writeScripts(outDir, ns, artifacts, cfg)
 
+	var repairResult *RepairResult
 	if cfg.Repair {
-		result := repairTask(cfg, outDir, task.TaskID)
+		result := repairTask(ctx, cfg, outDir, task.TaskID)
 		if result.Status == "error" {
-			return &result, fmt.Errorf("repair %s: %s", task.TaskID, result.Error)
+			return &result, nil, fmt.Errorf("repair %s: %s", task.TaskID, result.Error)
 		}
-		return &result, nil
+		repairResult = &result
 	}
 
-	return nil, nil
+	var verifyResult *VerifyResult
+	if vc != nil {
+		result := verifyTask(vc, outDir, task.TaskID, artifacts, cfg.ApplyTimeout)
+		verifyResult = &result
+	}
+
+	return repairResult, verifyResult, nil
 }
 
 func writeSuite(outDir string, task TaskMetadata, artifacts TaskArtifacts) {
@@ -209,7 +207,7 @@ func writeSuite(outDir string, task TaskMetadata, artifacts TaskArtifacts) {
 			cases = append(cases, map[string]interface{}{
 				"name":       c.Name,
 				"object":     cf,
-				"inventory":  artifacts.InventoryFiles[c.Name],
+				"inventory":  append(artifacts.InventoryFiles[c.Name], artifacts.ExtraInventory...),
 				"assertions": []map[string]interface{}{{"violations": violations}},
 			})
 		}
@@ -229,22 +227,26 @@ func writeSuite(outDir string, task TaskMetadata, artifacts TaskArtifacts) {
 	os.WriteFile(filepath.Join(outDir, "suite.yaml"), data, 0644)
 }
 
-func writeScripts(outDir, taskID string, artifacts TaskArtifacts) {
-	ns := "gk-" + taskID
-	var nsSetup, nsCleanup strings.Builder
+func writeScripts(outDir, ns string, artifacts TaskArtifacts, cfg Config) {
+	// extraNSCleanup covers namespaces beyond the main task namespace
+	// (e.g. ones referenced by inventory objects); task-cleanup itself
+	// drains and deletes the main namespace below.
+	var nsSetup, extraNSCleanup strings.Builder
 	for _, n := range artifacts.Namespaces {
 		if n == "default" || n == "kube-system" {
 			continue
 		}
 		fmt.Fprintf(&nsSetup, "kubectl delete namespace %q --ignore-not-found\n", n)
 		fmt.Fprintf(&nsSetup, "kubectl create namespace %q\n", n)
-		fmt.Fprintf(&nsSetup, "kubectl wait --for=jsonpath='{.status.phase}'=Active --timeout=120s namespace %q\n", n)
-		fmt.Fprintf(&nsCleanup, "kubectl delete namespace %q --ignore-not-found\n", n)
+		fmt.Fprintf(&nsSetup, "kubectl wait --for=jsonpath='{.status.phase}'=Active --timeout=%s namespace %q\n", cfg.NamespaceReadyTimeout, n)
+		if n != ns {
+			fmt.Fprintf(&extraNSCleanup, "kubectl delete namespace %q --ignore-not-found\n", n)
+		}
 	}
 
-	var resCleanup strings.Builder
+	var clusterResourceArgs strings.Builder
 	for _, r := range artifacts.ClusterResources {
-		fmt.Fprintf(&resCleanup, "kubectl delete %s %q --ignore-not-found\n", r.Kind, r.Name)
+		fmt.Fprintf(&clusterResourceArgs, " -cluster-resource %s/%s", r.Kind, r.Name)
 	}
 
 	setup := fmt.Sprintf(`#!/usr/bin/env bash
@@ -263,32 +265,36 @@ done
 for file in "$ARTIFACTS_DIR"/beta-*.yaml; do
   kubectl apply -f "$file"
 done
-for file in "$ARTIFACTS_DIR"/inventory-*.yaml "$ARTIFACTS_DIR"/alpha-*.yaml "$ARTIFACTS_DIR"/beta-*.yaml; do
-  kind="$(kubectl get -f "$file" -o jsonpath='{.kind}')"
-  case "$kind" in
-    Deployment|StatefulSet|DaemonSet)
-      kubectl rollout status -f "$file" --timeout=120s
-      ;;
-    ReplicaSet)
-      kubectl wait --for=condition=Available --timeout=120s -f "$file"
-      ;;
-    Pod)
-      kubectl wait --for=condition=Ready --timeout=120s -f "$file"
-      ;;
-    Job)
-      kubectl wait --for=condition=Complete --timeout=120s -f "$file"
-      ;;
-  esac
-done
+# Poll every applied object for readiness via task-apply, a Go-native
+# waiter (cmd/task-apply) that drives typed client-go status checks per
+# Kind instead of the old `+"`kubectl rollout status`"+`/`+"`kubectl wait`"+` case
+# statement, which only covered a few Kinds and gave poor diagnostics on a
+# stuck resource. Build it with `+"`go build -o task-apply ./cmd/task-apply`"+`
+# and put it on PATH before running setup.sh.
+WAIT_FILES=("$ARTIFACTS_DIR"/inventory-*.yaml "$ARTIFACTS_DIR"/alpha-*.yaml "$ARTIFACTS_DIR"/beta-*.yaml)
+if [ ${#WAIT_FILES[@]} -gt 0 ]; then
+  task-apply -namespace "$TASK_NAMESPACE" -timeout=%s "${WAIT_FILES[@]}"
+fi
 # Show deployed resources for debugging
 kubectl get all -n "$TASK_NAMESPACE" 2>/dev/null || true
 kubectl get ingress -n "$TASK_NAMESPACE" 2>/dev/null || true
 kubectl get hpa -n "$TASK_NAMESPACE" 2>/dev/null || true
 kubectl get pdb -n "$TASK_NAMESPACE" 2>/dev/null || true
 kubectl get clusterrolebinding 2>/dev/null | head -n 20 || true
-`, ns, strings.TrimSpace(nsSetup.String()))
+`, ns, strings.TrimSpace(nsSetup.String()), cfg.ApplyTimeout)
 
-	cleanup := fmt.Sprintf("#!/usr/bin/env bash\nset -euo pipefail\n%s%s", nsCleanup.String(), resCleanup.String())
+	cleanup := fmt.Sprintf(`#!/usr/bin/env bash
+set -euo pipefail
+%s# Drain and delete the task namespace the way `+"`kubectl drain`"+` tears
+# down a node - evicting pods so PodDisruptionBudgets are honored, then
+# deleting workload controllers, the namespace, and any cluster-scoped
+# resources - instead of the old flat `+"`kubectl delete namespace`"+`/
+# `+"`kubectl delete <kind> <name>`"+` list, which frequently left
+# finalizers pending and raced other tasks sharing a cluster. Build it
+# with `+"`go build -o task-cleanup ./cmd/task-cleanup`"+` and put it on
+# PATH before running cleanup.sh.
+task-cleanup -namespace %q -timeout=%s%s
+`, extraNSCleanup.String(), ns, cfg.CleanupTimeout, clusterResourceArgs.String())
 
 	os.WriteFile(filepath.Join(outDir, "setup.sh"), []byte(setup), 0755)
 	os.WriteFile(filepath.Join(outDir, "cleanup.sh"), []byte(cleanup), 0755)
@@ -296,6 +302,25 @@ kubectl get clusterrolebinding 2>/dev/null | head -n 20 || true
 
 // Helpers
 
+// buildDiscoveryClient builds a discovery client from kubeconfig for
+// ScopeResolver. It returns nil (rather than an error) when kubeconfig is
+// unset or the cluster is unreachable, so callers fall back to the
+// api-resources.json cache or the static clusterScopedKinds list.
+func buildDiscoveryClient(kubeconfig string) discovery.DiscoveryInterface {
+	if kubeconfig == "" {
+		return nil
+	}
+	restCfg, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return nil
+	}
+	client, err := discovery.NewDiscoveryClientForConfig(restCfg)
+	if err != nil {
+		return nil
+	}
+	return client
+}
+
 func copyFile(src, dst string) error {
 	data, err := os.ReadFile(src)
 	if err != nil {
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// requiredLabelsTemplate/Constraint/alpha/beta fixtures below are a minimal,
+// known-good Gatekeeper K8sRequiredLabels policy: beta omits the "team"
+// label and so is denied; alpha has it and is admitted. They exist purely
+// to give checkAdmission something real to evaluate in
+// TestRepairTaskFakeBackendSmoke.
+const requiredLabelsTemplate = `apiVersion: templates.gatekeeper.sh/v1
+kind: ConstraintTemplate
+metadata:
+  name: k8srequiredlabels
+spec:
+  crd:
+    spec:
+      names:
+        kind: K8sRequiredLabels
+      validation:
+        openAPIV3Schema:
+          type: object
+          properties:
+            labels:
+              type: array
+              items:
+                type: string
+  targets:
+    - target: admission.k8s.gatekeeper.sh
+      rego: |
+        package k8srequiredlabels
+
+        violation[{"msg": msg}] {
+          required := input.parameters.labels
+          provided := {label | input.review.object.metadata.labels[label]}
+          missing := required - provided
+          count(missing) > 0
+          msg := sprintf("missing required labels: %v", [missing])
+        }
+`
+
+const requiredLabelsConstraint = `apiVersion: constraints.gatekeeper.sh/v1beta1
+kind: K8sRequiredLabels
+metadata:
+  name: require-team-label
+spec:
+  match:
+    kinds:
+      - apiGroups: [""]
+        kinds: ["Pod"]
+  parameters:
+    labels: ["team"]
+`
+
+const compliantPod = `apiVersion: v1
+kind: Pod
+metadata:
+  name: alpha-pod
+  labels:
+    team: platform
+spec:
+  containers:
+    - name: app
+      image: nginx
+`
+
+const violatingPod = `apiVersion: v1
+kind: Pod
+metadata:
+  name: beta-pod
+spec:
+  containers:
+    - name: app
+      image: nginx
+`
+
+// TestWriteSuiteAndScriptsSmoke drives writeSuite and writeScripts against a
+// synthetic task and checks the files they're responsible for land on disk
+// with the content downstream tooling (gator, setup.sh, cleanup.sh) expects.
+func TestWriteSuiteAndScriptsSmoke(t *testing.T) {
+	outDir := t.TempDir()
+	task := TaskMetadata{
+		TaskID:    "k8srequiredlabels-demo",
+		SuiteName: "k8srequiredlabels",
+		TestName:  "require-team-label",
+		Cases: []TaskCase{
+			{Name: "alpha", Expected: "alpha", ObjectPath: "alpha.yaml"},
+			{Name: "beta", Expected: "beta", ObjectPath: "beta.yaml"},
+		},
+	}
+	artifacts := TaskArtifacts{
+		CaseFiles: map[string][]string{
+			"alpha": {"artifacts/alpha-1.yaml"},
+			"beta":  {"artifacts/beta-1.yaml"},
+		},
+		InventoryFiles: map[string][]string{},
+		Namespaces:     []string{"gk-k8srequiredlabels-demo"},
+	}
+
+	writeSuite(outDir, task, artifacts)
+	if _, err := os.Stat(filepath.Join(outDir, "suite.yaml")); err != nil {
+		t.Fatalf("writeSuite did not produce suite.yaml: %v", err)
+	}
+
+	cfg := Config{
+		ApplyTimeout:          2 * time.Minute,
+		CleanupTimeout:        2 * time.Minute,
+		NamespaceReadyTimeout: 2 * time.Minute,
+	}
+	writeScripts(outDir, "gk-k8srequiredlabels-demo", artifacts, cfg)
+
+	setup, err := os.ReadFile(filepath.Join(outDir, "setup.sh"))
+	if err != nil {
+		t.Fatalf("writeScripts did not produce setup.sh: %v", err)
+	}
+	if !strings.Contains(string(setup), "task-apply") || !strings.Contains(string(setup), "gk-k8srequiredlabels-demo") {
+		t.Fatalf("setup.sh missing expected content:\n%s", setup)
+	}
+
+	cleanup, err := os.ReadFile(filepath.Join(outDir, "cleanup.sh"))
+	if err != nil {
+		t.Fatalf("writeScripts did not produce cleanup.sh: %v", err)
+	}
+	if !strings.Contains(string(cleanup), "task-cleanup") || !strings.Contains(string(cleanup), "gk-k8srequiredlabels-demo") {
+		t.Fatalf("cleanup.sh missing expected content:\n%s", cleanup)
+	}
+}
+
+// TestBuildPromptFakeBackendSmoke checks that -ai-backend=fake wires in
+// FakePromptGenerator and that BuildPrompt returns its canned prompt without
+// any network access.
+func TestBuildPromptFakeBackendSmoke(t *testing.T) {
+	cfg := Config{AIBackend: "fake"}
+	cfg.PromptGenerators = buildPromptGenerators(&cfg)
+	cfg.AILimiter = newAILimiter(1)
+	if len(cfg.PromptGenerators) != 1 {
+		t.Fatalf("buildPromptGenerators(fake) = %d generators, want 1", len(cfg.PromptGenerators))
+	}
+	if _, ok := cfg.PromptGenerators[0].(*FakePromptGenerator); !ok {
+		t.Fatalf("buildPromptGenerators(fake) = %T, want *FakePromptGenerator", cfg.PromptGenerators[0])
+	}
+
+	prompt := BuildPrompt(context.Background(), cfg, PromptContext{TaskID: "k8srequiredlabels-demo"})
+	if prompt == "" {
+		t.Fatal("BuildPrompt returned an empty prompt for the fake backend")
+	}
+}
+
+// TestRepairTaskFakeBackendSmoke drives repairTask end to end against a real
+// (if trivial) Gatekeeper policy, with -repair-backend=fake standing in for
+// the LLM so the whole loop - prompt building, checkAdmission, and result
+// reporting - runs with no network access.
+func TestRepairTaskFakeBackendSmoke(t *testing.T) {
+	outDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(outDir, "artifacts"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFixture(t, filepath.Join(outDir, "template.yaml"), requiredLabelsTemplate)
+	writeFixture(t, filepath.Join(outDir, "constraint.yaml"), requiredLabelsConstraint)
+	writeFixture(t, filepath.Join(outDir, "artifacts", "alpha-1.yaml"), compliantPod)
+	writeFixture(t, filepath.Join(outDir, "artifacts", "beta-1.yaml"), violatingPod)
+
+	cfg := Config{RepairBackend: "fake", MaxRepairAttempts: 1, RepairTimeout: 2 * time.Minute}
+	repairLLM, err := buildRepairLLM(&cfg)
+	if err != nil {
+		t.Fatalf("buildRepairLLM(fake): %v", err)
+	}
+	cfg.RepairLLM = repairLLM
+
+	result := repairTask(context.Background(), cfg, outDir, "k8srequiredlabels-demo")
+	if result.Status != "no_changes" {
+		t.Fatalf("repairTask(fake) status = %q, want %q (error: %s)", result.Status, "no_changes", result.Error)
+	}
+}
+
+func writeFixture(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("writing fixture %s: %v", path, err)
+	}
+}
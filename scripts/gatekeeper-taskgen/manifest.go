@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -10,17 +11,26 @@ import (
 	"sigs.k8s.io/yaml"
 )
 
-// GenerateManifests processes task cases and generates artifact files
-func GenerateManifests(task TaskMetadata, outDir string) (TaskArtifacts, PromptContext, error) {
+// GenerateManifests processes task cases and generates artifact files. ns
+// is the namespace every manifest, the scoped constraint, and the prompt's
+// namespace context target - the same namespace generateTask resolved via
+// resolveTaskOverride and already used for rewriteConstraint and
+// writeScripts, so all four sites agree on where the task actually lives.
+func GenerateManifests(task TaskMetadata, outDir string, cfg Config, ns string) (TaskArtifacts, PromptContext, error) {
+	scope := cfg.ScopeResolver
 	os.MkdirAll(filepath.Join(outDir, "artifacts"), 0755)
 
-	defaultNS := "gk-" + task.TaskID
+	defaultNS := ns
 	artifacts := TaskArtifacts{
 		CaseFiles:      map[string][]string{},
 		InventoryFiles: map[string][]string{},
+		AdmissionFiles: map[string][]string{},
 	}
-	alphaIdx, betaIdx, invIdx := 1, 1, 1
+	alphaIdx, betaIdx, invIdx, admissionIdx := 1, 1, 1, 1
 	nsSet := map[string]bool{defaultNS: true}
+	admission := NewAdmissionReviewAdapter()
+	var hasAdmissionCases bool
+	var taskLintFindings []Finding
 
 	var templateTitle, templateDesc, templateYAML, constraintYAML string
 	var alphaExamples, betaExamples []string
@@ -42,35 +52,59 @@ func GenerateManifests(task TaskMetadata, outDir string) (TaskArtifacts, PromptC
 		}
 	}
 
-	// Read constraint
+	// Read constraint, scoped to defaultNS so the prompt matches what
+	// rewriteConstraint actually applies on disk.
 	if data, err := os.ReadFile(task.ConstraintPath); err == nil {
-		constraintYAML = string(data)
-		// Patch constraint for prompt (simple string replace for now to avoid overhead)
-		// This ensures Gemini sees the isolated namespace
-		constraintYAML = strings.Replace(constraintYAML, "- \"default\"", fmt.Sprintf("- %q", defaultNS), 1)
-		constraintYAML = strings.Replace(constraintYAML, "- default", fmt.Sprintf("- %q", defaultNS), 1)
+		scoped, err := NewConstraintScoper().Scope(data, defaultNS)
+		if err != nil {
+			scoped = data
+		}
+		constraintYAML = string(scoped)
 	}
 
 	for _, c := range task.Cases {
 		caseDocs, _ := readYAMLDocs(c.ObjectPath)
-		if len(caseDocs) == 0 || isAdmissionReview(caseDocs[0]) || !isDeployable(caseDocs[0]) {
+		if len(caseDocs) == 0 {
+			continue
+		}
+		caseDoc := caseDocs[0]
+		var admissionReq map[string]interface{}
+		if isAdmissionReview(caseDoc) {
+			obj, req, ok := admission.Adapt(caseDoc)
+			if !ok {
+				continue
+			}
+			caseDoc, admissionReq = obj, req
+		}
+		if !isDeployable(caseDoc) {
 			continue
 		}
 
-		// Load inventory docs
+		// Load inventory docs, adapting any that also ship as AdmissionReview
 		var invDocs []map[string]interface{}
 		for _, inv := range c.InventoryPaths {
-			if docs, _ := readYAMLDocs(inv); len(docs) > 0 && !isAdmissionReview(docs[0]) {
-				invDocs = append(invDocs, docs[0])
+			docs, _ := readYAMLDocs(inv)
+			if len(docs) == 0 {
+				continue
+			}
+			doc := docs[0]
+			if isAdmissionReview(doc) {
+				obj, _, ok := admission.Adapt(doc)
+				if !ok {
+					continue
+				}
+				doc = obj
 			}
+			invDocs = append(invDocs, doc)
 		}
 
 		// Build name map and collect docs
-		nameMap := map[string]string{}
+		nm := newNameMap()
 		type docInfo struct {
-			doc     map[string]interface{}
-			newName string
-			isInv   bool
+			doc       map[string]interface{}
+			newName   string
+			isInv     bool
+			admission map[string]interface{}
 		}
 		var allDocs []docInfo
 
@@ -78,31 +112,45 @@ func GenerateManifests(task TaskMetadata, outDir string) (TaskArtifacts, PromptC
 			name := fmt.Sprintf("resource-inventory-%02d", invIdx)
 			invIdx++
 			if orig := getStr(doc, "metadata", "name"); orig != "" {
-				nameMap[orig] = name
+				nm.set(getStr(doc, "kind"), nameMapNamespace(scope, doc, defaultNS), orig, name)
 			}
-			allDocs = append(allDocs, docInfo{doc, name, true})
+			allDocs = append(allDocs, docInfo{doc: doc, newName: name, isInv: true})
 		}
 
-		for _, doc := range caseDocs[:1] {
-			var name string
-			if c.Expected == "alpha" {
-				name = fmt.Sprintf("resource-alpha-%02d", alphaIdx)
-				alphaIdx++
-			} else {
-				name = fmt.Sprintf("resource-beta-%02d", betaIdx)
-				betaIdx++
-			}
-			if orig := getStr(doc, "metadata", "name"); orig != "" {
-				nameMap[orig] = name
+		var caseName string
+		if c.Expected == "alpha" {
+			caseName = fmt.Sprintf("resource-alpha-%02d", alphaIdx)
+			alphaIdx++
+		} else {
+			caseName = fmt.Sprintf("resource-beta-%02d", betaIdx)
+			betaIdx++
+		}
+		if orig := getStr(caseDoc, "metadata", "name"); orig != "" {
+			nm.set(getStr(caseDoc, "kind"), nameMapNamespace(scope, caseDoc, defaultNS), orig, caseName)
+		}
+		allDocs = append(allDocs, docInfo{doc: caseDoc, newName: caseName, admission: admissionReq})
+
+		// Rewrite and lint every doc; rewriteManifest's final pass is
+		// lintManifest, which may auto-fix doc in place (see manifestlint.go).
+		var caseFindings []Finding
+		for _, d := range allDocs {
+			findings := rewriteManifest(d.doc, d.newName, defaultNS, nm, task.TaskID, c.Expected, d.isInv, scope)
+			caseFindings = append(caseFindings, findings...)
+			if cfg.Verbose {
+				for _, f := range findings {
+					cfg.Log("  [%s] %s: %s\n", f.Severity, f.Sanitizer, f.Message)
+				}
 			}
-			allDocs = append(allDocs, docInfo{doc, name, false})
+		}
+		taskLintFindings = append(taskLintFindings, caseFindings...)
+		if hasError(caseFindings) {
+			return TaskArtifacts{}, PromptContext{}, fmt.Errorf("case %s/%s: manifestlint found a blocking issue (see lint findings above)", task.TaskID, c.Name)
 		}
 
-		// Rewrite and save
 		invFileIdx, caseFileIdx := 1, 1
 		for _, d := range allDocs {
-			rewriteManifest(d.doc, d.newName, defaultNS, nameMap, task.TaskID, c.Expected, d.isInv)
 			kind := getStr(d.doc, "kind")
+			apiVersion := getStr(d.doc, "apiVersion")
 			ns := getStr(d.doc, "metadata", "namespace")
 			if ns != "" {
 				nsSet[ns] = true
@@ -137,7 +185,7 @@ func GenerateManifests(task TaskMetadata, outDir string) (TaskArtifacts, PromptC
 				Kind:          kind,
 				Name:          d.newName,
 				Namespace:     ns,
-				ClusterScoped: isClusterScoped(kind),
+				ClusterScoped: scope.IsClusterScoped(apiVersion, kind),
 			})
 
 			if d.isInv {
@@ -148,9 +196,18 @@ func GenerateManifests(task TaskMetadata, outDir string) (TaskArtifacts, PromptC
 				artifacts.CaseFiles[c.Name] = append(artifacts.CaseFiles[c.Name], relPath)
 			}
 
-			if isClusterScoped(kind) {
+			if scope.IsClusterScoped(apiVersion, kind) {
 				artifacts.ClusterResources = append(artifacts.ClusterResources, ClusterResource{kind, d.newName})
 			}
+
+			if d.admission != nil {
+				admissionRelPath, err := writeAdmissionRequest(outDir, c.Expected, admissionIdx, d.admission)
+				if err == nil {
+					admissionIdx++
+					hasAdmissionCases = true
+					artifacts.AdmissionFiles[c.Name] = append(artifacts.AdmissionFiles[c.Name], admissionRelPath)
+				}
+			}
 		}
 	}
 
@@ -170,231 +227,54 @@ func GenerateManifests(task TaskMetadata, outDir string) (TaskArtifacts, PromptC
 	}
 
 	promptCtx := PromptContext{
-		TaskID:          task.TaskID,
-		Title:           templateTitle,
-		Description:     templateDesc,
-		TemplateYAML:    templateYAML,
-		ConstraintYAML:  constraintYAML,
-		AlphaExamples:   alphaExamples,
-		BetaExamples:    betaExamples,
-		Namespace:       defaultNS,
-		NamespacedKinds: sortedKeys(namespacedKindsSet),
-		ClusterKinds:    sortedKeys(clusterKindsSet),
-	}
-
-	return artifacts, promptCtx, nil
-}
-
-func rewriteManifest(doc map[string]interface{}, name, ns string, nameMap map[string]string, taskID, expected string, isInv bool) {
-	meta := ensureMap(doc, "metadata")
-	meta["name"] = name
-	if !isClusterScoped(getStr(doc, "kind")) {
-		meta["namespace"] = ns
-	}
-	labels := ensureMap(meta, "labels")
-	labels["k8s-ai-bench/task"] = taskID
-	labels["k8s-ai-bench/expected"] = expected
-	labels["k8s-ai-bench/inventory"] = fmt.Sprintf("%t", isInv)
-
-	kind := getStr(doc, "kind")
-	spec, _ := doc["spec"].(map[string]interface{})
-	switch kind {
-	case "HorizontalPodAutoscaler":
-		if ref, ok := spec["scaleTargetRef"].(map[string]interface{}); ok {
-			if n, ok := ref["name"].(string); ok {
-				ref["name"] = mapName(n, nameMap)
-			}
-		}
-	case "PersistentVolumeClaim":
-		if sc, ok := spec["storageClassName"].(string); ok {
-			spec["storageClassName"] = mapName(sc, nameMap)
-		}
-	case "StatefulSet":
-		updateVCT(spec, nameMap)
-		updatePodTemplate(spec, nameMap)
-	case "Deployment", "ReplicaSet", "DaemonSet":
-		updatePodTemplate(spec, nameMap)
-		fixReplicaCount(spec, expected)
-	case "Pod":
-		updatePodSpec(spec, nameMap)
-	case "RoleBinding", "ClusterRoleBinding":
-		updateRoleBinding(doc, nameMap, ns)
-	}
-
-	// Apply deployment fixes
-	fixInitContainers(doc)
-	fixBadImages(doc)
-}
-
-func updateVCT(spec map[string]interface{}, nameMap map[string]string) {
-	templates, _ := spec["volumeClaimTemplates"].([]interface{})
-	for _, t := range templates {
-		if claim, ok := t.(map[string]interface{}); ok {
-			if cs, ok := claim["spec"].(map[string]interface{}); ok {
-				if sc, ok := cs["storageClassName"].(string); ok {
-					cs["storageClassName"] = mapName(sc, nameMap)
-				}
-			}
-		}
-	}
-}
-
-func updatePodTemplate(spec map[string]interface{}, nameMap map[string]string) {
-	if t, ok := spec["template"].(map[string]interface{}); ok {
-		if ps, ok := t["spec"].(map[string]interface{}); ok {
-			updatePodSpec(ps, nameMap)
+		TaskID:            task.TaskID,
+		Title:             templateTitle,
+		Description:       templateDesc,
+		TemplateYAML:      templateYAML,
+		ConstraintYAML:    constraintYAML,
+		AlphaExamples:     alphaExamples,
+		BetaExamples:      betaExamples,
+		Namespace:         defaultNS,
+		NamespacedKinds:   sortedKeys(namespacedKindsSet),
+		ClusterKinds:      sortedKeys(clusterKindsSet),
+		HasAdmissionCases: hasAdmissionCases,
+	}
+
+	if len(taskLintFindings) > 0 {
+		if err := writeLintReport(outDir, taskLintFindings); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to write lint report for %s: %v\n", task.TaskID, err)
 		}
 	}
-}
 
-func updatePodSpec(spec map[string]interface{}, nameMap map[string]string) {
-	if sa, ok := spec["serviceAccountName"].(string); ok {
-		spec["serviceAccountName"] = mapName(sa, nameMap)
-	}
-	if vols, ok := spec["volumes"].([]interface{}); ok {
-		for _, v := range vols {
-			if vm, ok := v.(map[string]interface{}); ok {
-				if pvc, ok := vm["persistentVolumeClaim"].(map[string]interface{}); ok {
-					if cn, ok := pvc["claimName"].(string); ok {
-						pvc["claimName"] = mapName(cn, nameMap)
-					}
-				}
-			}
-		}
-	}
-}
-
-func updateRoleBinding(doc map[string]interface{}, nameMap map[string]string, ns string) {
-	if subjects, ok := doc["subjects"].([]interface{}); ok {
-		for _, s := range subjects {
-			if sm, ok := s.(map[string]interface{}); ok {
-				if sm["kind"] == "ServiceAccount" {
-					if n, ok := sm["name"].(string); ok {
-						sm["name"] = mapName(n, nameMap)
-					}
-					if sm["namespace"] == nil {
-						sm["namespace"] = ns
-					}
-				}
-			}
-		}
-	}
-	if ref, ok := doc["roleRef"].(map[string]interface{}); ok {
-		if n, ok := ref["name"].(string); ok {
-			ref["name"] = mapName(n, nameMap)
-		}
-	}
+	return artifacts, promptCtx, nil
 }
 
-// Deployment fixes - make manifests deployable without breaking test semantics
-
-// fixReplicaCount caps excessive replica counts while preserving alpha/beta distinction
-// Alpha stays at original (e.g., 3), Beta gets capped to 5 (still > limit, so still fails)
-func fixReplicaCount(spec map[string]interface{}, expected string) {
-	if expected != "beta" {
-		return
+// writeAdmissionRequest writes req as the JSON body of an AdmissionRequest
+// fixture so an evaluator can POST it directly to Gatekeeper's /v1/admit
+// endpoint without a live apply. Returns the path relative to outDir.
+func writeAdmissionRequest(outDir, expected string, idx int, req map[string]interface{}) (string, error) {
+	if err := os.MkdirAll(filepath.Join(outDir, "admission"), 0755); err != nil {
+		return "", err
 	}
-	const maxBetaReplicas = 5
-	if replicas, ok := spec["replicas"].(int); ok && replicas > maxBetaReplicas {
-		spec["replicas"] = maxBetaReplicas
+	relPath := fmt.Sprintf("admission/%s-%02d.json", expected, idx)
+	data, err := json.MarshalIndent(req, "", "  ")
+	if err != nil {
+		return "", err
 	}
-	if replicas, ok := spec["replicas"].(float64); ok && int(replicas) > maxBetaReplicas {
-		spec["replicas"] = maxBetaReplicas
+	if err := os.WriteFile(filepath.Join(outDir, relPath), data, 0644); err != nil {
+		return "", err
 	}
+	return relPath, nil
 }
 
-// fixInitContainers adds exit command to init containers that would run forever
-func fixInitContainers(doc map[string]interface{}) {
-	kind := getStr(doc, "kind")
-	var podSpec map[string]interface{}
-
-	switch kind {
-	case "Pod":
-		podSpec, _ = doc["spec"].(map[string]interface{})
-	case "Deployment", "ReplicaSet", "DaemonSet", "StatefulSet":
-		if spec, ok := doc["spec"].(map[string]interface{}); ok {
-			if template, ok := spec["template"].(map[string]interface{}); ok {
-				podSpec, _ = template["spec"].(map[string]interface{})
-			}
-		}
-	}
-
-	if podSpec == nil {
-		return
-	}
-
-	initContainers, ok := podSpec["initContainers"].([]interface{})
-	if !ok {
-		return
-	}
-
-	for _, c := range initContainers {
-		container, ok := c.(map[string]interface{})
-		if !ok {
-			continue
-		}
-		// Init containers need to exit for the pod to start.
-		// Override command/args with a simple exit for images that run servers.
-		image, _ := container["image"].(string)
-		if strings.Contains(image, "nginx") {
-			container["command"] = []interface{}{"sh", "-c", "exit 0"}
-			delete(container, "args")
-		} else if strings.Contains(image, "opa") {
-			// OPA image doesn't have sh, use built-in eval that exits
-			container["command"] = []interface{}{"opa", "eval", "true"}
-			delete(container, "args")
-		}
-	}
-}
-
-// fixBadImages replaces images that fail to pull with working alternatives
-// Only for images where the replacement doesn't affect the policy test
-func fixBadImages(doc map[string]interface{}) {
-	kind := getStr(doc, "kind")
-	var podSpec map[string]interface{}
-
-	switch kind {
-	case "Pod":
-		podSpec, _ = doc["spec"].(map[string]interface{})
-	case "Deployment", "ReplicaSet", "DaemonSet", "StatefulSet":
-		if spec, ok := doc["spec"].(map[string]interface{}); ok {
-			if template, ok := spec["template"].(map[string]interface{}); ok {
-				podSpec, _ = template["spec"].(map[string]interface{})
-			}
-		}
-	}
-
-	if podSpec == nil {
-		return
-	}
-
-	// Only fix specific images where replacement doesn't break test semantics
-	replacements := map[string]string{
-		"tomcat":      "nginx",      // required-probes: policy checks probes, not image
-		"nginx:1.7.9": "nginx:1.25", // old nginx tag doesn't exist
-	}
-
-	for _, key := range []string{"containers", "initContainers"} {
-		containers, ok := podSpec[key].([]interface{})
-		if !ok {
-			continue
-		}
-		for _, c := range containers {
-			container, ok := c.(map[string]interface{})
-			if !ok {
-				continue
-			}
-			image, ok := container["image"].(string)
-			if !ok {
-				continue
-			}
-			for bad, good := range replacements {
-				if image == bad {
-					container["image"] = good
-				}
-			}
-		}
+// nameMapNamespace is the namespace rewriteManifest's nameMap should key doc
+// under: "" for a cluster-scoped kind (matching the clusterScopedRef lookups
+// in manifest_transform.go), defaultNS otherwise.
+func nameMapNamespace(scope *ScopeResolver, doc map[string]interface{}, defaultNS string) string {
+	if scope.IsClusterScoped(getStr(doc, "apiVersion"), getStr(doc, "kind")) {
+		return ""
 	}
+	return defaultNS
 }
 
 // Validation helpers
@@ -432,10 +312,6 @@ func isDeployable(doc map[string]interface{}) bool {
 	return true
 }
 
-func isClusterScoped(kind string) bool {
-	return kind == "Namespace" || kind == "ClusterRole" || kind == "ClusterRoleBinding" || kind == "StorageClass"
-}
-
 // YAML helpers
 
 func readYAMLDocs(path string) ([]map[string]interface{}, error) {
@@ -481,10 +357,3 @@ func ensureMap(parent map[string]interface{}, key string) map[string]interface{}
 	parent[key] = m
 	return m
 }
-
-func mapName(name string, nameMap map[string]string) string {
-	if v, ok := nameMap[name]; ok {
-		return v
-	}
-	return name
-}
@@ -6,9 +6,11 @@ import (
 )
 
 // Manifest rewrites are grouped to make intent explicit:
-// 1) identity: isolate the task with stable names/namespaces/labels
-// 2) references: keep object references consistent with renamed resources
-// 3) deployability: safe tweaks that avoid stuck pods or image pull failures
+//  1. identity: isolate the task with stable names/namespaces/labels
+//  2. references: keep object references consistent with renamed resources
+//  3. deployability: safe tweaks that avoid stuck pods or image pull failures
+//  4. lint: a final Popeye-style pass that catches (and where possible,
+//     auto-fixes) everything else that would keep the manifest from deploying
 type manifestRewriteContext struct {
 	name     string
 	ns       string
@@ -18,7 +20,11 @@ type manifestRewriteContext struct {
 	isInv    bool
 }
 
-func rewriteManifest(doc map[string]interface{}, name, ns string, nameMap *nameMap, taskID, expected string, isInv bool) {
+// rewriteManifest applies every rewrite pass to doc and returns the lint
+// findings from the final pass. A SeverityError finding means doc is left
+// mutated up to that point - the caller is expected to drop the case/task
+// rather than use it.
+func rewriteManifest(doc map[string]interface{}, name, ns string, nameMap *nameMap, taskID, expected string, isInv bool, scope *ScopeResolver) []Finding {
 	ctx := manifestRewriteContext{
 		name:     name,
 		ns:       ns,
@@ -28,15 +34,17 @@ func rewriteManifest(doc map[string]interface{}, name, ns string, nameMap *nameM
 		isInv:    isInv,
 	}
 
-	applyIdentity(doc, ctx)
+	applyIdentity(doc, ctx, scope)
 	rewriteReferences(doc, ctx)
+	applyReplicaCountFix(doc, ctx.expected)
 	applyDeployabilityFixes(doc)
+	return lintManifest(doc, ctx)
 }
 
-func applyIdentity(doc map[string]interface{}, ctx manifestRewriteContext) {
+func applyIdentity(doc map[string]interface{}, ctx manifestRewriteContext, scope *ScopeResolver) {
 	meta := ensureMap(doc, "metadata")
 	meta["name"] = ctx.name
-	if !isClusterScoped(getStr(doc, "kind")) {
+	if !scope.IsClusterScoped(getStr(doc, "apiVersion"), getStr(doc, "kind")) {
 		meta["namespace"] = ctx.ns
 	}
 
@@ -46,88 +54,207 @@ func applyIdentity(doc map[string]interface{}, ctx manifestRewriteContext) {
 	labels["k8s-ai-bench/inventory"] = fmt.Sprintf("%t", ctx.isInv)
 }
 
+// refFieldSpec is a kustomize namereferencetransformer-style field spec: a
+// dotted path to a name field plus the kind it refers to. A "*" path
+// component fans out over every element of the list found at the preceding
+// key - e.g. {"containers", "*", "envFrom", "*", "configMapRef", "name"}
+// visits every container's every envFrom entry.
+type refFieldSpec struct {
+	path []string
+	// refKind is the kind passed to nameMap.mapName to resolve the
+	// renamed value.
+	refKind string
+	// clusterScopedRef is true when refKind is cluster-scoped, so the
+	// lookup namespace must be "" rather than the object's own namespace
+	// (e.g. a PersistentVolumeClaim's storageClassName).
+	clusterScopedRef bool
+	// nsSibling, if set, is a field alongside the leaf field that holds
+	// an explicit namespace for the reference (e.g. a webhook's
+	// clientConfig.service.namespace), overriding the object's own
+	// namespace when present.
+	nsSibling string
+}
+
+// podSpecRefSpecs are the name references that live inside a PodSpec,
+// shared by every pod-template-carrying workload kind (Deployment,
+// StatefulSet, DaemonSet, ReplicaSet, Job, CronJob) and by bare Pods. Kinds
+// register these once, prefixed with the path down to their embedded
+// PodSpec, rather than special-casing each workload kind by hand.
+var podSpecRefSpecs = buildPodSpecRefSpecs()
+
+func buildPodSpecRefSpecs() []refFieldSpec {
+	specs := []refFieldSpec{
+		{path: []string{"serviceAccountName"}, refKind: "ServiceAccount"},
+		{path: []string{"imagePullSecrets", "*", "name"}, refKind: "Secret"},
+		{path: []string{"volumes", "*", "persistentVolumeClaim", "claimName"}, refKind: "PersistentVolumeClaim"},
+		{path: []string{"volumes", "*", "configMap", "name"}, refKind: "ConfigMap"},
+		{path: []string{"volumes", "*", "secret", "secretName"}, refKind: "Secret"},
+	}
+	for _, containerList := range []string{"containers", "initContainers", "ephemeralContainers"} {
+		specs = append(specs,
+			refFieldSpec{path: []string{containerList, "*", "envFrom", "*", "configMapRef", "name"}, refKind: "ConfigMap"},
+			refFieldSpec{path: []string{containerList, "*", "envFrom", "*", "secretRef", "name"}, refKind: "Secret"},
+			refFieldSpec{path: []string{containerList, "*", "env", "*", "valueFrom", "configMapKeyRef", "name"}, refKind: "ConfigMap"},
+			refFieldSpec{path: []string{containerList, "*", "env", "*", "valueFrom", "secretKeyRef", "name"}, refKind: "Secret"},
+		)
+	}
+	return specs
+}
+
+// prefixedSpecs returns specs with prefix prepended to every path, for
+// reuse under a kind's nested PodSpec (or any other embedded struct).
+func prefixedSpecs(prefix []string, specs []refFieldSpec) []refFieldSpec {
+	out := make([]refFieldSpec, len(specs))
+	for i, s := range specs {
+		path := make([]string, 0, len(prefix)+len(s.path))
+		path = append(path, prefix...)
+		path = append(path, s.path...)
+		s.path = path
+		out[i] = s
+	}
+	return out
+}
+
+// refRegistry maps a Kind to the field specs describing every name
+// reference it carries. Adding a new referencing field to a known kind, or
+// support for an entirely new kind, is a matter of adding a table entry
+// here rather than writing a new rewrite function.
+var refRegistry = buildRefRegistry()
+
+func buildRefRegistry() map[string][]refFieldSpec {
+	statefulSetOnly := []refFieldSpec{
+		{path: []string{"spec", "volumeClaimTemplates", "*", "spec", "storageClassName"}, refKind: "StorageClass", clusterScopedRef: true},
+	}
+
+	reg := map[string][]refFieldSpec{
+		"Pod":         prefixedSpecs([]string{"spec"}, podSpecRefSpecs),
+		"Deployment":  prefixedSpecs([]string{"spec", "template", "spec"}, podSpecRefSpecs),
+		"ReplicaSet":  prefixedSpecs([]string{"spec", "template", "spec"}, podSpecRefSpecs),
+		"DaemonSet":   prefixedSpecs([]string{"spec", "template", "spec"}, podSpecRefSpecs),
+		"Job":         prefixedSpecs([]string{"spec", "template", "spec"}, podSpecRefSpecs),
+		"CronJob":     prefixedSpecs([]string{"spec", "jobTemplate", "spec", "template", "spec"}, podSpecRefSpecs),
+		"StatefulSet": append(prefixedSpecs([]string{"spec", "template", "spec"}, podSpecRefSpecs), statefulSetOnly...),
+
+		"PersistentVolumeClaim": {
+			{path: []string{"spec", "storageClassName"}, refKind: "StorageClass", clusterScopedRef: true},
+		},
+		"Ingress": {
+			{path: []string{"spec", "rules", "*", "http", "paths", "*", "backend", "service", "name"}, refKind: "Service"},
+			{path: []string{"spec", "defaultBackend", "service", "name"}, refKind: "Service"},
+		},
+		"MutatingWebhookConfiguration": {
+			{path: []string{"webhooks", "*", "clientConfig", "service", "name"}, refKind: "Service", nsSibling: "namespace"},
+		},
+
+		// Service.spec.selector, NetworkPolicy's pod/namespaceSelector and
+		// PodDisruptionBudget.spec.selector match resources by label
+		// equality rather than by name, so - same as kustomize's own
+		// NameReferenceTransformer - they're out of scope for this
+		// name-keyed table; they'd need a LabelTransformer instead.
+	}
+	return reg
+}
+
+// rewriteReferences rewrites every name reference a generated manifest
+// carries so it keeps pointing at the renamed resource. Most kinds are
+// handled entirely by refRegistry; HorizontalPodAutoscaler, RoleBinding and
+// ClusterRoleBinding are special-cased because the kind of the thing they
+// reference is itself a field on the object rather than fixed in the table.
 func rewriteReferences(doc map[string]interface{}, ctx manifestRewriteContext) {
 	kind := getStr(doc, "kind")
-	spec, _ := doc["spec"].(map[string]interface{})
+	for _, spec := range refRegistry[kind] {
+		applyRefSpec(doc, spec, ctx.nameMap, ctx.ns)
+	}
 
 	switch kind {
 	case "HorizontalPodAutoscaler":
-		if ref, ok := spec["scaleTargetRef"].(map[string]interface{}); ok {
-			refKind, _ := ref["kind"].(string)
-			if n, ok := ref["name"].(string); ok && refKind != "" {
-				ref["name"] = ctx.nameMap.mapName(refKind, ctx.ns, n)
-			}
-		}
-	case "PersistentVolumeClaim":
-		if sc, ok := spec["storageClassName"].(string); ok {
-			spec["storageClassName"] = ctx.nameMap.mapName("StorageClass", "", sc)
-		}
-	case "StatefulSet":
-		rewriteVolumeClaimTemplates(spec, ctx.nameMap)
-		rewritePodTemplateRefs(spec, ctx.nameMap, ctx.ns)
-	case "Deployment", "ReplicaSet", "DaemonSet":
-		rewritePodTemplateRefs(spec, ctx.nameMap, ctx.ns)
-		fixReplicaCount(spec, ctx.expected)
-	case "Pod":
-		rewritePodSpecRefs(spec, ctx.nameMap, ctx.ns)
+		rewriteScaleTargetRef(doc, ctx.nameMap, ctx.ns)
 	case "RoleBinding", "ClusterRoleBinding":
 		rewriteRoleBindingRefs(doc, ctx.nameMap, ctx.ns)
 	}
 }
 
-func rewriteVolumeClaimTemplates(spec map[string]interface{}, nameMap *nameMap) {
-	templates, _ := spec["volumeClaimTemplates"].([]interface{})
-	for _, t := range templates {
-		if claim, ok := t.(map[string]interface{}); ok {
-			if cs, ok := claim["spec"].(map[string]interface{}); ok {
-				if sc, ok := cs["storageClassName"].(string); ok {
-					cs["storageClassName"] = nameMap.mapName("StorageClass", "", sc)
-				}
+// applyRefSpec walks doc along spec.path and, if it finds a non-empty
+// string at the leaf, replaces it with its renamed counterpart.
+func applyRefSpec(doc map[string]interface{}, spec refFieldSpec, nameMap *nameMap, ns string) {
+	walkRefPath(doc, spec.path, spec, nameMap, ns)
+}
+
+func walkRefPath(node interface{}, path []string, spec refFieldSpec, nameMap *nameMap, ns string) {
+	m, ok := node.(map[string]interface{})
+	if !ok || len(path) == 0 {
+		return
+	}
+
+	if len(path) == 1 {
+		leaf := path[0]
+		n, ok := m[leaf].(string)
+		if !ok || n == "" {
+			return
+		}
+		refNS := ns
+		if spec.nsSibling != "" {
+			if sib, ok := m[spec.nsSibling].(string); ok && sib != "" {
+				refNS = sib
 			}
 		}
+		if spec.clusterScopedRef {
+			refNS = ""
+		}
+		m[leaf] = nameMap.mapName(spec.refKind, refNS, n)
+		return
 	}
-}
 
-func rewritePodTemplateRefs(spec map[string]interface{}, nameMap *nameMap, ns string) {
-	if t, ok := spec["template"].(map[string]interface{}); ok {
-		if ps, ok := t["spec"].(map[string]interface{}); ok {
-			rewritePodSpecRefs(ps, nameMap, ns)
+	key, rest := path[0], path[1:]
+	if rest[0] == "*" {
+		list, _ := m[key].([]interface{})
+		for _, e := range list {
+			walkRefPath(e, rest[1:], spec, nameMap, ns)
 		}
+		return
 	}
+
+	child, ok := m[key].(map[string]interface{})
+	if !ok {
+		return
+	}
+	walkRefPath(child, rest, spec, nameMap, ns)
 }
 
-func rewritePodSpecRefs(spec map[string]interface{}, nameMap *nameMap, ns string) {
-	if sa, ok := spec["serviceAccountName"].(string); ok {
-		spec["serviceAccountName"] = nameMap.mapName("ServiceAccount", ns, sa)
+// rewriteScaleTargetRef rewrites an HPA's scaleTargetRef.name, keyed by the
+// kind named in scaleTargetRef.kind rather than a kind fixed in the table.
+func rewriteScaleTargetRef(doc map[string]interface{}, nameMap *nameMap, ns string) {
+	spec, _ := doc["spec"].(map[string]interface{})
+	ref, ok := spec["scaleTargetRef"].(map[string]interface{})
+	if !ok {
+		return
 	}
-	if vols, ok := spec["volumes"].([]interface{}); ok {
-		for _, v := range vols {
-			if vm, ok := v.(map[string]interface{}); ok {
-				if pvc, ok := vm["persistentVolumeClaim"].(map[string]interface{}); ok {
-					if cn, ok := pvc["claimName"].(string); ok {
-						pvc["claimName"] = nameMap.mapName("PersistentVolumeClaim", ns, cn)
-					}
-				}
-			}
-		}
+	refKind, _ := ref["kind"].(string)
+	if n, ok := ref["name"].(string); ok && refKind != "" {
+		ref["name"] = nameMap.mapName(refKind, ns, n)
 	}
 }
 
+// rewriteRoleBindingRefs rewrites a RoleBinding/ClusterRoleBinding's
+// subjects and roleRef, keyed by the kind named on each subject and on
+// roleRef.kind rather than a kind fixed in the table.
 func rewriteRoleBindingRefs(doc map[string]interface{}, nameMap *nameMap, ns string) {
 	if subjects, ok := doc["subjects"].([]interface{}); ok {
 		for _, s := range subjects {
-			if sm, ok := s.(map[string]interface{}); ok {
-				if sm["kind"] == "ServiceAccount" {
-					if n, ok := sm["name"].(string); ok {
-						subjectNS, _ := sm["namespace"].(string)
-						if subjectNS == "" {
-							subjectNS = ns
-						}
-						sm["name"] = nameMap.mapName("ServiceAccount", subjectNS, n)
-					}
-					if sm["namespace"] == nil {
-						sm["namespace"] = ns
+			sm, ok := s.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if sm["kind"] == "ServiceAccount" {
+				if n, ok := sm["name"].(string); ok {
+					subjectNS, _ := sm["namespace"].(string)
+					if subjectNS == "" {
+						subjectNS = ns
 					}
+					sm["name"] = nameMap.mapName("ServiceAccount", subjectNS, n)
+				}
+				if sm["namespace"] == nil {
+					sm["namespace"] = ns
 				}
 			}
 		}
@@ -149,6 +276,18 @@ func rewriteRoleBindingRefs(doc map[string]interface{}, nameMap *nameMap, ns str
 
 // Deployment fixes - make manifests deployable without breaking test semantics
 
+// applyReplicaCountFix runs fixReplicaCount for Deployment, ReplicaSet and
+// DaemonSet (which has no spec.replicas, so nothing changes for it).
+// StatefulSet is left unchanged.
+func applyReplicaCountFix(doc map[string]interface{}, expected string) {
+	switch getStr(doc, "kind") {
+	case "Deployment", "ReplicaSet", "DaemonSet":
+		if spec, ok := doc["spec"].(map[string]interface{}); ok {
+			fixReplicaCount(spec, expected)
+		}
+	}
+}
+
 // fixReplicaCount caps excessive replica counts while preserving alpha/beta distinction
 // Alpha stays at original (e.g., 3), Beta gets capped to 5 (still > limit, so still fails)
 func fixReplicaCount(spec map[string]interface{}, expected string) {
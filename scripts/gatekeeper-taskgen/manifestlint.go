@@ -0,0 +1,723 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Severity classifies how serious a Finding is, in increasing order of
+// badness.
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarn
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarn:
+		return "warn"
+	case SeverityError:
+		return "error"
+	default:
+		return "info"
+	}
+}
+
+// Finding is one problem a Sanitizer found in a generated manifest.
+type Finding struct {
+	Sanitizer string
+	Severity  Severity
+	Message   string
+}
+
+// Sanitizer inspects a rewritten manifest document and reports problems that
+// would keep it from deploying cleanly, the way Popeye's resource sanitizers
+// flag a cluster's live resources. A Sanitizer may auto-fix an issue by
+// mutating doc in place, in which case it reports the fix as a SeverityInfo
+// Finding; otherwise it leaves doc alone and reports SeverityWarn (surfaced
+// in lint-report.json) or SeverityError (aborts the task - see hasError).
+type Sanitizer interface {
+	Name() string
+	Sanitize(doc map[string]interface{}) []Finding
+}
+
+// defaultSanitizers is the registry run over every generated manifest. It's
+// a plain slice (not a sync.Map or similar) because registration only
+// happens at init time from this package; callers extending the benchmark
+// with project-specific sanitizers can append to it from another file in
+// the same package.
+var defaultSanitizers = []Sanitizer{
+	badImageTagSanitizer{},
+	initContainerEntrypointSanitizer{},
+	probeTargetPortSanitizer{},
+	storageClassSanitizer{},
+	rbacSubjectSanitizer{},
+	missingResourceRequestsSanitizer{},
+	selectorMatchesTemplateSanitizer{},
+	duplicateServicePortNameSanitizer{},
+	invalidLabelKeySanitizer{},
+	pvcMinimumSizeSanitizer{},
+	emptyContainerImageSanitizer{},
+	emptyServiceSelectorSanitizer{},
+	duplicateContainerNameSanitizer{},
+	hostPortSanitizer{},
+}
+
+// RegisterSanitizer adds s to the registry used by RunSanitizers. It exists
+// so project-specific policies can be added without editing rewriteManifest.
+func RegisterSanitizer(s Sanitizer) {
+	defaultSanitizers = append(defaultSanitizers, s)
+}
+
+// RunSanitizers runs every registered Sanitizer over doc and returns all
+// findings, in registry order.
+func RunSanitizers(doc map[string]interface{}) []Finding {
+	var findings []Finding
+	for _, s := range defaultSanitizers {
+		findings = append(findings, s.Sanitize(doc)...)
+	}
+	return findings
+}
+
+// lintManifest is rewriteManifest's final pass: it runs every registered
+// Sanitizer plus the checks that need ctx (nameMap, namespace) rather than
+// just doc, and returns every Finding produced.
+func lintManifest(doc map[string]interface{}, ctx manifestRewriteContext) []Finding {
+	findings := RunSanitizers(doc)
+	findings = append(findings, danglingServiceAccountFinding(doc, ctx)...)
+	return findings
+}
+
+// danglingServiceAccountFinding flags a serviceAccountName that rewriteReferences
+// left unchanged because it isn't one of the ServiceAccounts this task
+// generated - i.e. it's relying on one already present on the test cluster,
+// which won't exist on a fresh kind cluster.
+func danglingServiceAccountFinding(doc map[string]interface{}, ctx manifestRewriteContext) []Finding {
+	podSpec := podSpecForWorkload(doc)
+	if podSpec == nil {
+		return nil
+	}
+	sa, _ := podSpec["serviceAccountName"].(string)
+	if sa == "" || sa == "default" || ctx.nameMap.has("ServiceAccount", ctx.ns, sa) {
+		return nil
+	}
+	// rewriteReferences has already run, so a generated ServiceAccount shows
+	// up here under its new name.
+	for key, renamed := range ctx.nameMap.entries {
+		if key.kind == "ServiceAccount" && key.namespace == ctx.ns && renamed == sa {
+			return nil
+		}
+	}
+	return []Finding{{
+		Sanitizer: "dangling-service-account",
+		Severity:  SeverityWarn,
+		Message:   fmt.Sprintf("serviceAccountName %q does not match any ServiceAccount generated for this task and must already exist on the cluster", sa),
+	}}
+}
+
+// writeLintReport writes every lint Finding collected across a task's
+// manifests to lint-report.json in outDir, so a reviewer can see what was
+// auto-fixed or flagged without re-running with -verbose.
+func writeLintReport(outDir string, findings []Finding) error {
+	type reportEntry struct {
+		Sanitizer string `json:"sanitizer"`
+		Severity  string `json:"severity"`
+		Message   string `json:"message"`
+	}
+	entries := make([]reportEntry, len(findings))
+	for i, f := range findings {
+		entries[i] = reportEntry{Sanitizer: f.Sanitizer, Severity: f.Severity.String(), Message: f.Message}
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(outDir, "lint-report.json"), data, 0644)
+}
+
+// hasError reports whether findings contains a SeverityError entry.
+func hasError(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// badImageTagSanitizer flags image references known to fail to pull or
+// resolve on a fresh test cluster. fixBadImages already auto-fixes the ones
+// it knows about; this sanitizer exists so unfixed/new cases surface instead
+// of silently producing a stuck pod.
+type badImageTagSanitizer struct{}
+
+func (badImageTagSanitizer) Name() string { return "bad-image-tag" }
+
+func (badImageTagSanitizer) Sanitize(doc map[string]interface{}) []Finding {
+	podSpec := podSpecForWorkload(doc)
+	if podSpec == nil {
+		return nil
+	}
+	var findings []Finding
+	for _, key := range []string{"containers", "initContainers"} {
+		containers, _ := podSpec[key].([]interface{})
+		for _, c := range containers {
+			container, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			image, _ := container["image"].(string)
+			if knownBadImages[image] {
+				findings = append(findings, Finding{
+					Sanitizer: "bad-image-tag",
+					Severity:  SeverityWarn,
+					Message:   fmt.Sprintf("image %q is known to fail to pull on a fresh test cluster", image),
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// initContainerEntrypointSanitizer flags init containers whose image runs a
+// server ENTRYPOINT (so it never exits) without fixInitContainers having an
+// override for it.
+type initContainerEntrypointSanitizer struct{}
+
+func (initContainerEntrypointSanitizer) Name() string { return "init-container-entrypoint" }
+
+func (initContainerEntrypointSanitizer) Sanitize(doc map[string]interface{}) []Finding {
+	podSpec := podSpecForWorkload(doc)
+	if podSpec == nil {
+		return nil
+	}
+	initContainers, _ := podSpec["initContainers"].([]interface{})
+	var findings []Finding
+	for _, c := range initContainers {
+		container, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if _, hasCommand := container["command"]; hasCommand {
+			continue // already overridden (by us or the source manifest)
+		}
+		image, _ := container["image"].(string)
+		if !serverEntrypointImages[baseImageName(image)] {
+			continue
+		}
+		findings = append(findings, Finding{
+			Sanitizer: "init-container-entrypoint",
+			Severity:  SeverityWarn,
+			Message:   fmt.Sprintf("init container image %q runs a server and never exits without a command override", image),
+		})
+	}
+	return findings
+}
+
+// probeTargetPortSanitizer flags readiness/liveness probes targeting a named
+// or numeric port that no container in the pod actually exposes.
+type probeTargetPortSanitizer struct{}
+
+func (probeTargetPortSanitizer) Name() string { return "probe-target-port" }
+
+func (probeTargetPortSanitizer) Sanitize(doc map[string]interface{}) []Finding {
+	podSpec := podSpecForWorkload(doc)
+	if podSpec == nil {
+		return nil
+	}
+	containers, _ := podSpec["containers"].([]interface{})
+	var findings []Finding
+	for _, c := range containers {
+		container, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		exposed := exposedPorts(container)
+		for _, probeKey := range []string{"readinessProbe", "livenessProbe"} {
+			probe, ok := container[probeKey].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			port := probeTargetPort(probe)
+			if port == "" || len(exposed) == 0 {
+				continue
+			}
+			if !exposed[port] {
+				findings = append(findings, Finding{
+					Sanitizer: "probe-target-port",
+					Severity:  SeverityError,
+					Message:   fmt.Sprintf("%s targets port %q which container %q does not expose", probeKey, port, container["name"]),
+				})
+			}
+		}
+	}
+	return findings
+}
+
+func exposedPorts(container map[string]interface{}) map[string]bool {
+	ports, _ := container["ports"].([]interface{})
+	exposed := map[string]bool{}
+	for _, p := range ports {
+		pm, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if name, ok := pm["name"].(string); ok && name != "" {
+			exposed[name] = true
+		}
+		exposed[fmt.Sprintf("%v", intOrFloat(pm["containerPort"]))] = true
+	}
+	return exposed
+}
+
+func probeTargetPort(probe map[string]interface{}) string {
+	for _, handler := range []string{"httpGet", "tcpSocket", "grpc"} {
+		h, ok := probe[handler].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if name, ok := h["port"].(string); ok {
+			return name
+		}
+		if port := intOrFloat(h["port"]); port != 0 {
+			return fmt.Sprintf("%v", port)
+		}
+	}
+	return ""
+}
+
+func intOrFloat(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case float64:
+		return int(n)
+	default:
+		return 0
+	}
+}
+
+// storageClassSanitizer flags PVCs referencing a storageClassName that won't
+// exist on the test cluster.
+type storageClassSanitizer struct{}
+
+func (storageClassSanitizer) Name() string { return "storage-class" }
+
+func (storageClassSanitizer) Sanitize(doc map[string]interface{}) []Finding {
+	if getStr(doc, "kind") != "PersistentVolumeClaim" {
+		return nil
+	}
+	spec, _ := doc["spec"].(map[string]interface{})
+	sc, _ := spec["storageClassName"].(string)
+	if sc == "" || knownStorageClasses[sc] {
+		return nil
+	}
+	return []Finding{{
+		Sanitizer: "storage-class",
+		Severity:  SeverityWarn,
+		Message:   fmt.Sprintf("storageClassName %q is not provisioned on the test cluster", sc),
+	}}
+}
+
+// rbacSubjectSanitizer flags RoleBinding/ClusterRoleBinding subjects that
+// reference a blank name, which gator/kubectl will reject.
+type rbacSubjectSanitizer struct{}
+
+func (rbacSubjectSanitizer) Name() string { return "rbac-subject" }
+
+func (rbacSubjectSanitizer) Sanitize(doc map[string]interface{}) []Finding {
+	kind := getStr(doc, "kind")
+	if kind != "RoleBinding" && kind != "ClusterRoleBinding" {
+		return nil
+	}
+	subjects, _ := doc["subjects"].([]interface{})
+	var findings []Finding
+	for _, s := range subjects {
+		sm, ok := s.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if name, _ := sm["name"].(string); name == "" {
+			findings = append(findings, Finding{
+				Sanitizer: "rbac-subject",
+				Severity:  SeverityError,
+				Message:   fmt.Sprintf("%s has a subject with no resolved name", kind),
+			})
+		}
+	}
+	return findings
+}
+
+var knownBadImages = map[string]bool{
+	"tomcat":      true,
+	"nginx:1.7.9": true,
+}
+
+var serverEntrypointImages = map[string]bool{
+	"nginx": true,
+	"opa":   true,
+}
+
+var knownStorageClasses = map[string]bool{
+	"standard": true,
+}
+
+func baseImageName(image string) string {
+	if i := strings.IndexAny(image, "@:"); i >= 0 {
+		return image[:i]
+	}
+	return image
+}
+
+const (
+	defaultCPURequest    = "50m"
+	defaultMemoryRequest = "64Mi"
+)
+
+// missingResourceRequestsSanitizer auto-fixes containers that have no
+// resources.requests. A LimitRange on the test cluster can reject pods
+// that don't specify one, independent of whatever policy the task exists
+// to test.
+type missingResourceRequestsSanitizer struct{}
+
+func (missingResourceRequestsSanitizer) Name() string { return "missing-resource-requests" }
+
+func (missingResourceRequestsSanitizer) Sanitize(doc map[string]interface{}) []Finding {
+	podSpec := podSpecForWorkload(doc)
+	if podSpec == nil {
+		return nil
+	}
+	containers, _ := podSpec["containers"].([]interface{})
+	var findings []Finding
+	for _, c := range containers {
+		container, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		resources := ensureMap(container, "resources")
+		requests := ensureMap(resources, "requests")
+		var added bool
+		if _, ok := requests["cpu"]; !ok {
+			requests["cpu"] = defaultCPURequest
+			added = true
+		}
+		if _, ok := requests["memory"]; !ok {
+			requests["memory"] = defaultMemoryRequest
+			added = true
+		}
+		if added {
+			findings = append(findings, Finding{
+				Sanitizer: "missing-resource-requests",
+				Severity:  SeverityInfo,
+				Message:   fmt.Sprintf("container %v had no resources.requests - set defaults (%s cpu / %s memory)", container["name"], defaultCPURequest, defaultMemoryRequest),
+			})
+		}
+	}
+	return findings
+}
+
+// selectorMatchesTemplateSanitizer auto-fixes a Deployment/ReplicaSet/
+// DaemonSet/StatefulSet with no spec.selector.matchLabels by deriving one
+// from the pod template's labels - the API server rejects a workload whose
+// selector doesn't match its own template. A selector that's present but
+// conflicts with the template can't be safely auto-fixed, so it blocks.
+type selectorMatchesTemplateSanitizer struct{}
+
+func (selectorMatchesTemplateSanitizer) Name() string { return "selector-matches-template" }
+
+func (selectorMatchesTemplateSanitizer) Sanitize(doc map[string]interface{}) []Finding {
+	kind := getStr(doc, "kind")
+	if kind != "Deployment" && kind != "ReplicaSet" && kind != "DaemonSet" && kind != "StatefulSet" {
+		return nil
+	}
+	spec, _ := doc["spec"].(map[string]interface{})
+	if spec == nil {
+		return nil
+	}
+	template, _ := spec["template"].(map[string]interface{})
+	templateMeta, _ := template["metadata"].(map[string]interface{})
+	templateLabels, _ := templateMeta["labels"].(map[string]interface{})
+
+	selector, _ := spec["selector"].(map[string]interface{})
+	matchLabels, _ := selector["matchLabels"].(map[string]interface{})
+
+	if len(matchLabels) == 0 {
+		if len(templateLabels) == 0 {
+			return []Finding{{
+				Sanitizer: "selector-matches-template",
+				Severity:  SeverityError,
+				Message:   fmt.Sprintf("%s has no spec.selector and no pod template labels to derive one from", kind),
+			}}
+		}
+		copied := make(map[string]interface{}, len(templateLabels))
+		for k, v := range templateLabels {
+			copied[k] = v
+		}
+		ensureMap(spec, "selector")["matchLabels"] = copied
+		return []Finding{{
+			Sanitizer: "selector-matches-template",
+			Severity:  SeverityInfo,
+			Message:   fmt.Sprintf("%s had no spec.selector.matchLabels - set it to the pod template's labels", kind),
+		}}
+	}
+
+	for k, v := range matchLabels {
+		if templateLabels[k] != v {
+			return []Finding{{
+				Sanitizer: "selector-matches-template",
+				Severity:  SeverityError,
+				Message:   fmt.Sprintf("%s spec.selector.matchLabels[%q]=%v does not match pod template label %v", kind, k, v, templateLabels[k]),
+			}}
+		}
+	}
+	return nil
+}
+
+// duplicateServicePortNameSanitizer auto-fixes a Service with more than one
+// port sharing the same name, which the API server rejects outright.
+type duplicateServicePortNameSanitizer struct{}
+
+func (duplicateServicePortNameSanitizer) Name() string { return "duplicate-service-port-name" }
+
+func (duplicateServicePortNameSanitizer) Sanitize(doc map[string]interface{}) []Finding {
+	if getStr(doc, "kind") != "Service" {
+		return nil
+	}
+	spec, _ := doc["spec"].(map[string]interface{})
+	ports, _ := spec["ports"].([]interface{})
+	seen := map[string]int{}
+	var findings []Finding
+	for _, p := range ports {
+		pm, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := pm["name"].(string)
+		if name == "" {
+			continue
+		}
+		seen[name]++
+		if seen[name] > 1 {
+			renamed := fmt.Sprintf("%s-%d", name, seen[name])
+			pm["name"] = renamed
+			findings = append(findings, Finding{
+				Sanitizer: "duplicate-service-port-name",
+				Severity:  SeverityInfo,
+				Message:   fmt.Sprintf("Service port name %q was duplicated - renamed to %q", name, renamed),
+			})
+		}
+	}
+	return findings
+}
+
+// labelNameRe matches the "name" part of a Kubernetes label key (the part
+// after an optional "prefix/"): alphanumeric, up to 63 chars, with
+// '-', '_', '.' allowed in the middle.
+var labelNameRe = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]{0,61}[A-Za-z0-9])?$`)
+
+// invalidLabelKeySanitizer blocks manifests whose labels use a key the API
+// server will reject, rather than letting the apply fail with a much less
+// actionable error later in the pipeline.
+type invalidLabelKeySanitizer struct{}
+
+func (invalidLabelKeySanitizer) Name() string { return "invalid-label-key" }
+
+func (invalidLabelKeySanitizer) Sanitize(doc map[string]interface{}) []Finding {
+	meta, _ := doc["metadata"].(map[string]interface{})
+	labels, _ := meta["labels"].(map[string]interface{})
+	var findings []Finding
+	for key := range labels {
+		name := key
+		if i := strings.Index(key, "/"); i >= 0 {
+			name = key[i+1:]
+		}
+		if name == "" || !labelNameRe.MatchString(name) {
+			findings = append(findings, Finding{
+				Sanitizer: "invalid-label-key",
+				Severity:  SeverityError,
+				Message:   fmt.Sprintf("label key %q is not a valid Kubernetes label name and will be rejected by the API server", key),
+			})
+		}
+	}
+	return findings
+}
+
+// minPVCSize is the smallest volume size the test cluster's default
+// StorageClass will provision.
+const minPVCSize = "1Gi"
+
+// pvcMinimumSizeSanitizer auto-fixes a PVC requesting less storage than the
+// test StorageClass's minimum, which would otherwise leave the PVC stuck
+// Pending forever.
+type pvcMinimumSizeSanitizer struct{}
+
+func (pvcMinimumSizeSanitizer) Name() string { return "pvc-minimum-size" }
+
+func (pvcMinimumSizeSanitizer) Sanitize(doc map[string]interface{}) []Finding {
+	if getStr(doc, "kind") != "PersistentVolumeClaim" {
+		return nil
+	}
+	spec, _ := doc["spec"].(map[string]interface{})
+	resources, _ := spec["resources"].(map[string]interface{})
+	requests, _ := resources["requests"].(map[string]interface{})
+	size, _ := requests["storage"].(string)
+	if size == "" {
+		return nil
+	}
+	qty, err := resource.ParseQuantity(size)
+	if err != nil {
+		return nil
+	}
+	if qty.Cmp(resource.MustParse(minPVCSize)) >= 0 {
+		return nil
+	}
+	requests["storage"] = minPVCSize
+	return []Finding{{
+		Sanitizer: "pvc-minimum-size",
+		Severity:  SeverityInfo,
+		Message:   fmt.Sprintf("storage request %q is below the test StorageClass's minimum - bumped to %s", size, minPVCSize),
+	}}
+}
+
+// emptyContainerImageSanitizer blocks a container with no image, which
+// would otherwise fail to schedule with an error far removed from the
+// generation step that produced it.
+type emptyContainerImageSanitizer struct{}
+
+func (emptyContainerImageSanitizer) Name() string { return "empty-container-image" }
+
+func (emptyContainerImageSanitizer) Sanitize(doc map[string]interface{}) []Finding {
+	podSpec := podSpecForWorkload(doc)
+	if podSpec == nil {
+		return nil
+	}
+	var findings []Finding
+	for _, key := range []string{"containers", "initContainers"} {
+		containers, _ := podSpec[key].([]interface{})
+		for _, c := range containers {
+			container, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if image, _ := container["image"].(string); image == "" {
+				findings = append(findings, Finding{
+					Sanitizer: "empty-container-image",
+					Severity:  SeverityError,
+					Message:   fmt.Sprintf("container %v has no image and will fail to schedule", container["name"]),
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// emptyServiceSelectorSanitizer warns about a Service with no selector,
+// which never gets Endpoints populated (short of a manually-managed
+// Endpoints/EndpointSlice, which generated tasks don't ship) unless it's
+// deliberately an ExternalName service.
+type emptyServiceSelectorSanitizer struct{}
+
+func (emptyServiceSelectorSanitizer) Name() string { return "empty-service-selector" }
+
+func (emptyServiceSelectorSanitizer) Sanitize(doc map[string]interface{}) []Finding {
+	if getStr(doc, "kind") != "Service" {
+		return nil
+	}
+	spec, _ := doc["spec"].(map[string]interface{})
+	if t, _ := spec["type"].(string); t == "ExternalName" {
+		return nil
+	}
+	selector, _ := spec["selector"].(map[string]interface{})
+	if len(selector) > 0 {
+		return nil
+	}
+	return []Finding{{
+		Sanitizer: "empty-service-selector",
+		Severity:  SeverityWarn,
+		Message:   "Service has no spec.selector - it won't route to any pods unless Endpoints are managed manually",
+	}}
+}
+
+// duplicateContainerNameSanitizer blocks a workload whose pod template
+// repeats a container name across containers/initContainers, which the API
+// server rejects outright.
+type duplicateContainerNameSanitizer struct{}
+
+func (duplicateContainerNameSanitizer) Name() string { return "duplicate-container-name" }
+
+func (duplicateContainerNameSanitizer) Sanitize(doc map[string]interface{}) []Finding {
+	podSpec := podSpecForWorkload(doc)
+	if podSpec == nil {
+		return nil
+	}
+	seen := map[string]bool{}
+	var findings []Finding
+	for _, key := range []string{"containers", "initContainers"} {
+		containers, _ := podSpec[key].([]interface{})
+		for _, c := range containers {
+			container, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _ := container["name"].(string)
+			if name == "" {
+				continue
+			}
+			if seen[name] {
+				findings = append(findings, Finding{
+					Sanitizer: "duplicate-container-name",
+					Severity:  SeverityError,
+					Message:   fmt.Sprintf("container name %q is used more than once and will be rejected by the API server", name),
+				})
+				continue
+			}
+			seen[name] = true
+		}
+	}
+	return findings
+}
+
+// hostPortSanitizer warns about containers binding a hostPort, which can
+// collide with another task's pod landing on the same test cluster node.
+type hostPortSanitizer struct{}
+
+func (hostPortSanitizer) Name() string { return "host-port" }
+
+func (hostPortSanitizer) Sanitize(doc map[string]interface{}) []Finding {
+	podSpec := podSpecForWorkload(doc)
+	if podSpec == nil {
+		return nil
+	}
+	containers, _ := podSpec["containers"].([]interface{})
+	var findings []Finding
+	for _, c := range containers {
+		container, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		ports, _ := container["ports"].([]interface{})
+		for _, p := range ports {
+			pm, ok := p.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if hp := intOrFloat(pm["hostPort"]); hp != 0 {
+				findings = append(findings, Finding{
+					Sanitizer: "host-port",
+					Severity:  SeverityWarn,
+					Message:   fmt.Sprintf("container %v sets hostPort %d, which can conflict with other tasks sharing the same node", container["name"], hp),
+				})
+			}
+		}
+	}
+	return findings
+}
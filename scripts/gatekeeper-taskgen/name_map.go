@@ -33,6 +33,14 @@ func (nm *nameMap) mapName(kind, namespace, name string) string {
 	return name
 }
 
+// has reports whether kind/namespace/name was registered with set - i.e.
+// whether it refers to a resource this task actually generated, rather than
+// one presumed to already exist on the cluster.
+func (nm *nameMap) has(kind, namespace, name string) bool {
+	_, ok := nm.entries[nameKey{kind: kind, namespace: namespace, name: name}]
+	return ok
+}
+
 type nameAllocator struct {
 	used map[nameKey]bool
 }
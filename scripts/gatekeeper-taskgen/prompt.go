@@ -4,22 +4,105 @@ import (
 	"context"
 	"fmt"
 	"strings"
-
-	"google.golang.org/genai"
 )
 
-// BuildPrompt generates a task prompt, using Gemini if available
-func BuildPrompt(cfg Config, ctx PromptContext) string {
-	if cfg.GeminiClient != nil {
-		if prompt, err := generatePromptWithGemini(cfg.GeminiClient, ctx); err == nil {
+// BuildPrompt generates a task prompt, preferring cfg.PromptGenerators (in
+// order, or scored against each other in Ensemble mode) and falling back to
+// the plain template prompt only if all of them fail. Every PromptGenerator
+// call goes through cfg.AILimiter so a wide -jobs worker pool doesn't blow
+// through the configured provider's rate limit.
+func BuildPrompt(ctx context.Context, cfg Config, promptCtx PromptContext) string {
+	if cfg.Ensemble {
+		if prompt, ok := buildEnsemblePrompt(ctx, cfg, promptCtx); ok {
+			return prompt
+		}
+		return buildFallbackPrompt(promptCtx)
+	}
+
+	for _, gen := range cfg.PromptGenerators {
+		prompt, err := generateWithLimiter(ctx, cfg, gen, promptCtx)
+		if err == nil {
 			return prompt
-		} else if cfg.Verbose {
-			fmt.Printf("  Gemini fallback for %s: %v\n", ctx.TaskID, err)
 		}
+		if cfg.Verbose {
+			cfg.Log("  %T fallback for %s: %v\n", gen, promptCtx.TaskID, err)
+		}
+	}
+	return buildFallbackPrompt(promptCtx)
+}
+
+// generateWithLimiter runs gen.Generate under cfg.AILimiter, so the limiter
+// accounts for every PromptGenerator call the same way regardless of
+// Ensemble mode.
+func generateWithLimiter(ctx context.Context, cfg Config, gen PromptGenerator, promptCtx PromptContext) (string, error) {
+	if err := cfg.AILimiter.acquire(ctx); err != nil {
+		return "", err
+	}
+	defer cfg.AILimiter.release()
+	return gen.Generate(ctx, promptCtx)
+}
+
+// buildFallbackPrompt is the non-AI prompt used when every PromptGenerator
+// fails, in Ensemble mode as well (candidate scores only pick among
+// successful candidates; there is no minimum score).
+func buildFallbackPrompt(ctx PromptContext) string {
+	if ctx.IsMutationTask {
+		return buildMutationPrompt(ctx)
 	}
 	return buildTemplatePrompt(ctx)
 }
 
+// buildEnsemblePrompt asks every configured generator for a candidate and
+// keeps the highest-scoring one. ok is false if every generator failed.
+func buildEnsemblePrompt(ctx context.Context, cfg Config, promptCtx PromptContext) (best string, ok bool) {
+	bestScore := 0
+	for _, gen := range cfg.PromptGenerators {
+		candidate, err := generateWithLimiter(ctx, cfg, gen, promptCtx)
+		if err != nil {
+			if cfg.Verbose {
+				cfg.Log("  %T ensemble candidate failed for %s: %v\n", gen, promptCtx.TaskID, err)
+			}
+			continue
+		}
+		if score := scorePrompt(candidate, promptCtx); !ok || score > bestScore {
+			best, bestScore, ok = candidate, score, true
+		}
+	}
+	return best, ok
+}
+
+// scorePrompt rates a candidate prompt against the meta-prompt's own rules:
+// a constraint-detection prompt must end with the "VIOLATING: <resource
+// name>" instruction; a mutation fix-suggestion prompt must end with the
+// "CHANGED: <field path> -> <value>" instruction instead. Either way it must
+// not leak the alpha/beta naming convention the benchmark relies on staying
+// hidden from the model under test. Higher is better.
+func scorePrompt(prompt string, ctx PromptContext) int {
+	trimmed := strings.TrimSpace(prompt)
+	lower := strings.ToLower(trimmed)
+	score := 0
+
+	marker := "VIOLATING: <resource name>"
+	if ctx.IsMutationTask {
+		marker = "CHANGED: <field path> -> <value>"
+	}
+	if strings.Contains(trimmed, marker) {
+		score += 10
+	}
+	for _, leak := range []string{"alpha", "beta", "naming convention", "naming pattern"} {
+		if strings.Contains(lower, leak) {
+			score -= 10
+		}
+	}
+	switch {
+	case len(trimmed) < 50:
+		score -= 5
+	case len(trimmed) > 2000:
+		score -= 2
+	}
+	return score
+}
+
 func buildTemplatePrompt(ctx PromptContext) string {
 	var b strings.Builder
 	b.WriteString("Can you check my Kubernetes cluster for resources that violate this policy?\n\n")
@@ -53,9 +136,36 @@ func buildTemplatePrompt(ctx PromptContext) string {
 	return b.String()
 }
 
-func generatePromptWithGemini(client *genai.Client, ctx PromptContext) (string, error) {
-	geminiCtx := context.Background()
+// buildMutationPrompt is buildTemplatePrompt's counterpart for a
+// gk-mutation-* task: instead of asking which resources violate a
+// constraint, it shows the pre-mutation resource and asks which fields the
+// mutation policy would change and to what value. This turns the task into
+// a fix-suggestion exercise rather than a detection one.
+func buildMutationPrompt(ctx PromptContext) string {
+	var b strings.Builder
+	b.WriteString("My cluster has an admission policy that automatically rewrites resources before they're created. Given this resource, can you tell me which fields it will change and what it will set them to?\n\n")
+
+	if ctx.Title != "" {
+		fmt.Fprintf(&b, "Policy: %s\n", ctx.Title)
+	}
+	if ctx.Description != "" {
+		fmt.Fprintf(&b, "Details: %s\n", ctx.Description)
+	}
+	if ctx.MutationInputYAML != "" {
+		fmt.Fprintf(&b, "\nResource as submitted:\n```yaml\n%s\n```\n", ctx.MutationInputYAML)
+	}
+
+	b.WriteString("\nRespond with one line per changed field in this exact format:\n")
+	b.WriteString("CHANGED: <field path> -> <value>\n")
+	return b.String()
+}
 
+// buildMetaPrompt builds the meta-prompt sent to every PromptGenerator,
+// asking it to write the actual task prompt for ctx.
+func buildMetaPrompt(ctx PromptContext) string {
+	if ctx.IsMutationTask {
+		return buildMutationMetaPrompt(ctx)
+	}
 	var metaPrompt strings.Builder
 	metaPrompt.WriteString(`You are helping generate a task prompt for a Kubernetes policy compliance benchmark.
 
@@ -129,19 +239,54 @@ Generate only the task prompt text, nothing else. Do not include markdown format
 Do not mention anything about resource naming patterns or conventions.
 	The prompt should end with strict instructions to use the "VIOLATING: <resource name>" format for every violation found.`)
 
-	result, err := client.Models.GenerateContent(geminiCtx, "gemini-2.0-flash", genai.Text(metaPrompt.String()), nil)
-	if err != nil {
-		return "", fmt.Errorf("gemini API error: %w", err)
-	}
+	return metaPrompt.String()
+}
 
-	if len(result.Candidates) == 0 || len(result.Candidates[0].Content.Parts) == 0 {
-		return "", fmt.Errorf("empty response from Gemini")
-	}
+// buildMutationMetaPrompt is buildMetaPrompt's counterpart for a
+// gk-mutation-* task, asking the generator to write a fix-suggestion prompt
+// instead of a detection prompt.
+func buildMutationMetaPrompt(ctx PromptContext) string {
+	var metaPrompt strings.Builder
+	metaPrompt.WriteString(`You are helping generate a task prompt for a Kubernetes admission-mutation benchmark.
+
+The benchmark tests whether an AI can predict how a mutating admission policy will rewrite a resource before it's
+persisted. The AI being tested is shown the resource as submitted and must say which fields the policy changes and
+what it sets them to - it is NOT told what the policy's mutators are.
+
+Generate a clear, concise task prompt that:
+1. Sounds like a real human request (first-person or direct ask), not a role-play instruction
+2. Explains what the mutation policy does in natural language, keep it concise
+3. Asks the AI to identify which fields will change and their new values
+4. Includes the resource as submitted, verbatim
+
+IMPORTANT: Do NOT reveal the mutator's spec.location, value, or any other implementation detail from the policy
+definition below - that's the answer the AI is supposed to work out. Describe only what the policy is for.
 
-	text := result.Candidates[0].Content.Parts[0].Text
-	if text == "" {
-		return "", fmt.Errorf("empty text in Gemini response")
+Required Output Format:
+For each changed field, the AI must print exactly one line:
+CHANGED: <field path> -> <value>
+
+Policy Information:
+`)
+
+	if ctx.Title != "" {
+		fmt.Fprintf(&metaPrompt, "Title: %s\n", ctx.Title)
 	}
+	if ctx.Description != "" {
+		fmt.Fprintf(&metaPrompt, "Description: %s\n", ctx.Description)
+	}
+	if ctx.MutationInputYAML != "" {
+		resource := ctx.MutationInputYAML
+		if len(resource) > 2000 {
+			resource = resource[:2000] + "\n... (truncated)"
+		}
+		fmt.Fprintf(&metaPrompt, "\nResource as submitted:\n```yaml\n%s\n```\n", resource)
+	}
+
+	metaPrompt.WriteString(`
+Generate only the task prompt text, nothing else. Do not include markdown formatting.
+Do not reveal the mutator's field path or target value directly - describe the policy's intent instead.
+The prompt should end with strict instructions to use the "CHANGED: <field path> -> <value>" format for every changed field.`)
 
-	return strings.TrimSpace(text), nil
+	return metaPrompt.String()
 }
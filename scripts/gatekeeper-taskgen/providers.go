@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// buildPromptGenerators wires up the PromptGenerators cfg.AIBackend selects.
+// The default (cfg.AIBackend == "") enables one PromptGenerator per LLM API
+// key found in the environment, so -ensemble has every configured provider
+// to draw from. cfg.GeminiClient is also populated when GEMINI_API_KEY is
+// set, so buildRepairLLM's gemini backend can reuse it for -repair.
+func buildPromptGenerators(cfg *Config) []PromptGenerator {
+	if cfg.AIBackend == "fake" {
+		fmt.Println("Fake prompt generator enabled (-ai-backend fake)")
+		return []PromptGenerator{&FakePromptGenerator{}}
+	}
+
+	var generators []PromptGenerator
+
+	if cfg.AIBackend == "" || cfg.AIBackend == "gemini" {
+		if apiKey := os.Getenv("GEMINI_API_KEY"); apiKey != "" {
+			client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+				APIKey:  apiKey,
+				Backend: genai.BackendGeminiAPI,
+			})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to initialize Gemini client: %v\n", err)
+			} else {
+				cfg.GeminiClient = client
+				generators = append(generators, &GeminiPromptGenerator{Client: client})
+				fmt.Println("Gemini prompt generator enabled")
+			}
+		}
+	}
+
+	if cfg.AIBackend == "" || cfg.AIBackend == "openai" {
+		if apiKey := os.Getenv("OPENAI_API_KEY"); apiKey != "" {
+			baseURL := os.Getenv("OPENAI_BASE_URL")
+			if baseURL == "" {
+				baseURL = "https://api.openai.com/v1"
+			}
+			model := os.Getenv("OPENAI_MODEL")
+			if model == "" {
+				model = "gpt-4o-mini"
+			}
+			generators = append(generators, &OpenAIPromptGenerator{BaseURL: baseURL, APIKey: apiKey, Model: model})
+			fmt.Printf("OpenAI-compatible prompt generator enabled (%s, %s)\n", baseURL, model)
+		}
+	}
+
+	if cfg.AIBackend == "" || cfg.AIBackend == "anthropic" {
+		if apiKey := os.Getenv("ANTHROPIC_API_KEY"); apiKey != "" {
+			model := os.Getenv("ANTHROPIC_MODEL")
+			if model == "" {
+				model = "claude-3-5-haiku-20241022"
+			}
+			generators = append(generators, &AnthropicPromptGenerator{APIKey: apiKey, Model: model})
+			fmt.Printf("Anthropic prompt generator enabled (%s)\n", model)
+		}
+	}
+
+	return generators
+}
+
+// PromptGenerator produces a task prompt from ctx using an LLM. Generate
+// returns an error (rather than falling back itself) when the backing API
+// is unreachable, rate-limited, or returns an unusable response, so BuildPrompt
+// can move on to the next generator.
+type PromptGenerator interface {
+	Generate(ctx context.Context, promptCtx PromptContext) (string, error)
+}
+
+// GeminiPromptGenerator generates prompts via the Gemini API.
+type GeminiPromptGenerator struct {
+	Client *genai.Client
+	Model  string // defaults to "gemini-2.0-flash"
+}
+
+func (g *GeminiPromptGenerator) Generate(ctx context.Context, promptCtx PromptContext) (string, error) {
+	model := g.Model
+	if model == "" {
+		model = "gemini-2.0-flash"
+	}
+
+	result, err := g.Client.Models.GenerateContent(ctx, model, genai.Text(buildMetaPrompt(promptCtx)), nil)
+	if err != nil {
+		return "", fmt.Errorf("gemini API error: %w", err)
+	}
+	if len(result.Candidates) == 0 || len(result.Candidates[0].Content.Parts) == 0 {
+		return "", fmt.Errorf("empty response from Gemini")
+	}
+	text := result.Candidates[0].Content.Parts[0].Text
+	if text == "" {
+		return "", fmt.Errorf("empty text in Gemini response")
+	}
+	return strings.TrimSpace(text), nil
+}
+
+// OpenAIPromptGenerator generates prompts via any OpenAI-compatible chat
+// completions endpoint - OpenAI itself, or a self-hosted vLLM/Ollama/Together
+// deployment that speaks the same API, selected by pointing BaseURL at it.
+type OpenAIPromptGenerator struct {
+	BaseURL string
+	APIKey  string
+	Model   string
+}
+
+func (g *OpenAIPromptGenerator) Generate(ctx context.Context, promptCtx PromptContext) (string, error) {
+	reqBody, err := json.Marshal(map[string]interface{}{
+		"model": g.Model,
+		"messages": []map[string]string{
+			{"role": "user", "content": buildMetaPrompt(promptCtx)},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(g.BaseURL, "/")+"/chat/completions", bytes.NewReader(reqBody))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if g.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+g.APIKey)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("openai-compatible API error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("openai-compatible API returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var parsed struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return "", fmt.Errorf("parsing openai-compatible response: %w", err)
+	}
+	if len(parsed.Choices) == 0 || parsed.Choices[0].Message.Content == "" {
+		return "", fmt.Errorf("empty response from openai-compatible API")
+	}
+	return strings.TrimSpace(parsed.Choices[0].Message.Content), nil
+}
+
+// AnthropicPromptGenerator generates prompts via the Anthropic Messages API.
+type AnthropicPromptGenerator struct {
+	APIKey string
+	Model  string
+}
+
+func (g *AnthropicPromptGenerator) Generate(ctx context.Context, promptCtx PromptContext) (string, error) {
+	reqBody, err := json.Marshal(map[string]interface{}{
+		"model":      g.Model,
+		"max_tokens": 1024,
+		"messages": []map[string]string{
+			{"role": "user", "content": buildMetaPrompt(promptCtx)},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.anthropic.com/v1/messages", bytes.NewReader(reqBody))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", g.APIKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("anthropic API error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("anthropic API returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var parsed struct {
+		Content []struct {
+			Text string `json:"text"`
+		} `json:"content"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return "", fmt.Errorf("parsing anthropic response: %w", err)
+	}
+	if len(parsed.Content) == 0 || parsed.Content[0].Text == "" {
+		return "", fmt.Errorf("empty response from Anthropic")
+	}
+	return strings.TrimSpace(parsed.Content[0].Text), nil
+}
+
+// FakePromptGenerator returns a deterministic fixture prompt without any
+// network call, so -ai-backend=fake can exercise run(), writeSuite,
+// writeScripts, and repairTask end-to-end in CI with no API key.
+type FakePromptGenerator struct{}
+
+func (f *FakePromptGenerator) Generate(ctx context.Context, promptCtx PromptContext) (string, error) {
+	return fmt.Sprintf("Deploy resources that comply with %s, then identify any that violate it.", promptCtx.Title), nil
+}
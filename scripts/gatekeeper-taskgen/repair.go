@@ -1,24 +1,53 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"sort"
 	"strings"
 	"time"
-
-	"google.golang.org/genai"
 )
 
-const repairModel = "gemini-2.5-flash"
+// RepairAttempt records one pass through repairTask's loop: the diff the
+// LLM proposed and the real admission verdict it produced once applied.
+type RepairAttempt struct {
+	N int `json:"n"`
+	// Diff is the (header-normalized) diff the LLM proposed this attempt.
+	// When FullRewrite is true it is the diff that failed to apply, not the
+	// full file that was swapped in instead.
+	Diff string `json:"diff,omitempty"`
+	// FullRewrite is true when the diff couldn't be fuzzy-applied and this
+	// attempt fell back to swapping in a full re-written file instead.
+	FullRewrite bool   `json:"fullRewrite,omitempty"`
+	Applied     bool   `json:"applied"`
+	AlphaOK     bool   `json:"alphaOK"` // alpha correctly stayed un-denied
+	BetaOK      bool   `json:"betaOK"`  // beta correctly ended up denied
+	Feedback    string `json:"feedback,omitempty"`
+}
+
+// RepairResult is repairTask's outcome for one task, plus the full
+// trajectory of attempts that got it there (or didn't).
+type RepairResult struct {
+	TaskID   string          `json:"taskID"`
+	Status   string          `json:"status"` // "repaired", "no_changes", "exhausted", "error"
+	FilePath string          `json:"filePath,omitempty"`
+	Diff     string          `json:"diff,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	Attempts []RepairAttempt `json:"attempts,omitempty"`
+}
 
-func repairTask(cfg Config, outDir, taskID string) RepairResult {
-	if cfg.GeminiClient == nil {
-		return RepairResult{TaskID: taskID, Status: "error", Error: "GEMINI_API_KEY not set"}
+// repairTask drives a verification-guided repair loop for one task's beta
+// manifest: it asks the LLM for a diff, applies it to a scratch copy, and
+// checks the real admission verdict for both alpha and beta before trusting
+// it. A candidate that doesn't hold - beta still passes, or alpha now fails
+// - is fed back into a follow-up prompt, up to cfg.MaxRepairAttempts times.
+// parent bounds the whole loop together with cfg.RepairTimeout, and is
+// cancelled early by run()'s -fail-fast.
+func repairTask(parent context.Context, cfg Config, outDir, taskID string) RepairResult {
+	if cfg.RepairLLM == nil {
+		return RepairResult{TaskID: taskID, Status: "error", Error: "no repair LLM configured"}
 	}
 
 	alphaPath, betaPath, err := findAlphaBeta(outDir)
@@ -26,16 +55,13 @@ func repairTask(cfg Config, outDir, taskID string) RepairResult {
 		return RepairResult{TaskID: taskID, Status: "error", Error: err.Error()}
 	}
 
-	// Find inventory files
 	inventoryPaths, _ := findInventory(outDir)
 
-	constraintPath := filepath.Join(outDir, "constraint.yaml")
-	templatePath := filepath.Join(outDir, "template.yaml")
-	constraintYAML, err := os.ReadFile(constraintPath)
+	constraintYAML, err := os.ReadFile(filepath.Join(outDir, "constraint.yaml"))
 	if err != nil {
 		return RepairResult{TaskID: taskID, Status: "error", Error: err.Error()}
 	}
-	templateYAML, err := os.ReadFile(templatePath)
+	templateYAML, err := os.ReadFile(filepath.Join(outDir, "template.yaml"))
 	if err != nil {
 		return RepairResult{TaskID: taskID, Status: "error", Error: err.Error()}
 	}
@@ -48,7 +74,6 @@ func repairTask(cfg Config, outDir, taskID string) RepairResult {
 		return RepairResult{TaskID: taskID, Status: "error", Error: err.Error()}
 	}
 
-	// Read inventory files
 	var inventoryYAMLs []string
 	for _, invPath := range inventoryPaths {
 		if data, err := os.ReadFile(invPath); err == nil {
@@ -56,33 +81,182 @@ func repairTask(cfg Config, outDir, taskID string) RepairResult {
 		}
 	}
 
-	prompt := buildRepairPrompt(taskID, betaPath, string(constraintYAML), string(templateYAML), string(alphaYAML), string(betaYAML), inventoryYAMLs)
-	ctx := context.Background()
-	result, err := cfg.GeminiClient.Models.GenerateContent(ctx, repairModel, genai.Text(prompt), nil)
-	if err != nil {
-		return RepairResult{TaskID: taskID, Status: "error", Error: fmt.Sprintf("gemini API error: %v", err)}
+	maxAttempts := cfg.MaxRepairAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 1
 	}
-	text, err := extractGeminiText(result)
-	if err != nil {
-		return RepairResult{TaskID: taskID, Status: "error", Error: err.Error()}
+
+	repairTimeout := cfg.RepairTimeout
+	if repairTimeout <= 0 {
+		repairTimeout = 10 * time.Minute
 	}
+	ctx, cancel := context.WithTimeout(parent, repairTimeout)
+	defer cancel()
+
+	result := RepairResult{TaskID: taskID, FilePath: betaPath}
+	currentBeta := betaYAML
+	var feedback string
+
+	for n := 1; n <= maxAttempts; n++ {
+		prompt := buildRepairPrompt(taskID, betaPath, string(constraintYAML), string(templateYAML), string(alphaYAML), string(currentBeta), inventoryYAMLs, feedback)
+		text, err := generateRepairDiff(ctx, cfg, prompt)
+		if err != nil {
+			result.Status = "error"
+			result.Error = fmt.Sprintf("%s error: %v", cfg.RepairLLM.Name(), err)
+			return result
+		}
+
+		cleaned := stripCodeFences(text)
+		if strings.Contains(strings.ToUpper(cleaned), "NO_CHANGES") {
+			attempt := RepairAttempt{N: n}
+			verdict, vErr := checkAdmission(templateYAML, constraintYAML, currentBeta)
+			if vErr == nil && verdict.Denied {
+				if cfg.Verbose {
+					cfg.Log("Repair %s: NO_CHANGES, verified denied\n", taskID)
+				}
+				result.Status = "no_changes"
+				result.Attempts = append(result.Attempts, attempt)
+				return result
+			}
+			attempt.Feedback = fmt.Sprintf("you said NO_CHANGES, but beta is not actually denied by the policy: %s", admissionFeedback(vErr, verdict.Messages))
+			feedback = attempt.Feedback
+			result.Attempts = append(result.Attempts, attempt)
+			continue
+		}
+
+		diff := normalizeDiff(cleaned, filepath.ToSlash(betaPath))
+		attempt := RepairAttempt{N: n, Diff: diff}
+
+		candidate, err := applyDiff(currentBeta, diff)
+		if err != nil {
+			rewritten, rErr := requestFullRewrite(ctx, cfg, taskID, string(constraintYAML), string(templateYAML), string(alphaYAML), string(currentBeta), inventoryYAMLs, err.Error())
+			if rErr != nil {
+				attempt.Feedback = fmt.Sprintf("your diff failed to apply (%v), and the full-rewrite fallback also failed: %v", err, rErr)
+				feedback = attempt.Feedback
+				result.Attempts = append(result.Attempts, attempt)
+				continue
+			}
+			candidate = rewritten
+			attempt.FullRewrite = true
+		}
+		attempt.Applied = true
+
+		alphaVerdict, alphaErr := checkAdmission(templateYAML, constraintYAML, alphaYAML)
+		betaVerdict, betaErr := checkAdmission(templateYAML, constraintYAML, candidate)
+		attempt.AlphaOK = alphaErr == nil && !alphaVerdict.Denied
+		attempt.BetaOK = betaErr == nil && betaVerdict.Denied
+
+		if attempt.AlphaOK && attempt.BetaOK {
+			if err := os.WriteFile(betaPath, candidate, 0644); err != nil {
+				result.Status = "error"
+				result.Error = fmt.Sprintf("writing repaired beta: %v", err)
+				result.Attempts = append(result.Attempts, attempt)
+				return result
+			}
+			if cfg.Verbose {
+				cfg.Log("Repair %s: repaired after %d attempt(s)\n", taskID, n)
+			}
+			result.Status = "repaired"
+			result.Diff = diff
+			if attempt.FullRewrite {
+				// No unified diff applied cleanly - record the full
+				// replacement content instead of the diff that failed.
+				result.Diff = string(candidate)
+			}
+			result.Attempts = append(result.Attempts, attempt)
+			return result
+		}
 
-	cleaned := stripCodeFences(text)
-	if strings.Contains(strings.ToUpper(cleaned), "NO_CHANGES") {
-		if cfg.Verbose {
-			fmt.Printf("Repair %s: NO_CHANGES\n", taskID)
+		var reasons []string
+		if !attempt.AlphaOK {
+			reasons = append(reasons, "alpha is now unexpectedly DENIED: "+admissionFeedback(alphaErr, alphaVerdict.Messages))
 		}
-		return RepairResult{TaskID: taskID, Status: "no_changes", FilePath: betaPath}
+		if !attempt.BetaOK {
+			reasons = append(reasons, "beta is still NOT denied - it must violate the policy: "+admissionFeedback(betaErr, betaVerdict.Messages))
+		}
+		attempt.Feedback = strings.Join(reasons, "; ")
+		feedback = attempt.Feedback
+		currentBeta = candidate // keep iterating from the latest candidate so the next diff's context lines still match
+		result.Attempts = append(result.Attempts, attempt)
+	}
+
+	result.Status = "exhausted"
+	result.Error = fmt.Sprintf("gave up after %d attempt(s)", maxAttempts)
+	return result
+}
+
+// admissionFeedback renders a checkAdmission outcome for a follow-up prompt.
+func admissionFeedback(err error, messages []string) string {
+	if err != nil {
+		return fmt.Sprintf("admission check itself failed: %v", err)
+	}
+	if len(messages) == 0 {
+		return "no denial message"
+	}
+	return strings.Join(messages, "; ")
+}
+
+// requestFullRewrite is the fallback for when applyDiff can't locate one of
+// the LLM's hunks even with fuzzy matching: instead of giving up, it asks
+// cfg.RepairLLM for the complete corrected beta file and swaps that in
+// atomically, rather than leaving the repair loop stuck on an unapplyable
+// diff.
+func requestFullRewrite(ctx context.Context, cfg Config, taskID, constraintYAML, templateYAML, alphaYAML, betaYAML string, inventoryYAMLs []string, applyErr string) ([]byte, error) {
+	prompt := buildFullRewritePrompt(taskID, constraintYAML, templateYAML, alphaYAML, betaYAML, inventoryYAMLs, applyErr)
+	text, err := generateRepairDiff(ctx, cfg, prompt)
+	if err != nil {
+		return nil, fmt.Errorf("%s error: %w", cfg.RepairLLM.Name(), err)
 	}
+	return []byte(stripCodeFences(text) + "\n"), nil
+}
 
-	diff := normalizeDiff(cleaned, filepath.ToSlash(betaPath))
-	if cfg.Verbose {
-		fmt.Printf("Repair %s: applying diff\n", taskID)
+// generateRepairDiff runs cfg.RepairLLM.GenerateDiff under cfg.AILimiter,
+// the repair-loop counterpart to prompt.go's generateWithLimiter.
+func generateRepairDiff(ctx context.Context, cfg Config, prompt string) (string, error) {
+	if err := cfg.AILimiter.acquire(ctx); err != nil {
+		return "", err
 	}
-	if err := applyPatch(diff); err != nil {
-		return RepairResult{TaskID: taskID, Status: "error", FilePath: betaPath, Diff: diff, Error: err.Error()}
+	defer cfg.AILimiter.release()
+	return cfg.RepairLLM.GenerateDiff(ctx, prompt)
+}
+
+func buildFullRewritePrompt(taskID, constraintYAML, templateYAML, alphaYAML, betaYAML string, inventoryYAMLs []string, applyErr string) string {
+	constraint := truncateString(constraintYAML, 3000)
+	template := truncateString(templateYAML, 3000)
+	alphaBlock := fmt.Sprintf("```yaml\n%s\n```", strings.TrimSpace(alphaYAML))
+	betaBlock := fmt.Sprintf("```yaml\n%s\n```", strings.TrimSpace(betaYAML))
+
+	var inventorySection string
+	if len(inventoryYAMLs) > 0 {
+		var invBlocks []string
+		for i, inv := range inventoryYAMLs {
+			if i >= 3 {
+				break
+			}
+			invBlocks = append(invBlocks, fmt.Sprintf("```yaml\n%s\n```", strings.TrimSpace(inv)))
+		}
+		inventorySection = fmt.Sprintf("\nInventory (existing resources in cluster that beta may need to conflict with):\n%s\n", strings.Join(invBlocks, "\n"))
 	}
-	return RepairResult{TaskID: taskID, Status: "repaired", FilePath: betaPath, Diff: diff}
+
+	prompt := fmt.Sprintf(
+		"You are editing Kubernetes manifests for a Gatekeeper policy benchmark.\n\n"+
+			"Your previous unified diff for this beta manifest could not be applied: %s\n\n"+
+			"Instead of a diff, return the COMPLETE, corrected beta manifest YAML - nothing else, no explanation, no code fence markers.\n\n"+
+			"Task: The beta manifest must VIOLATE the policy below. The alpha manifest is COMPLIANT and shown only for reference - do not return it.\n"+
+			"Preserve metadata.name, metadata.namespace, and all labels from the current beta manifest.\n\n"+
+			"Constraint:\n%s\n\n"+
+			"Template:\n%s\n"+
+			"%s\n"+
+			"Alpha manifest (COMPLIANT - for reference only):\n%s\n\n"+
+			"Current beta manifest (rewrite this so it VIOLATES the policy):\n%s\n",
+		applyErr,
+		constraint,
+		template,
+		inventorySection,
+		alphaBlock,
+		betaBlock,
+	)
+	return strings.TrimSpace(prompt)
 }
 
 func findAlphaBeta(outDir string) (string, string, error) {
@@ -104,7 +278,7 @@ func findInventory(outDir string) ([]string, error) {
 	return matches, nil
 }
 
-func buildRepairPrompt(taskID, targetPath, constraintYAML, templateYAML, alphaYAML, betaYAML string, inventoryYAMLs []string) string {
+func buildRepairPrompt(taskID, targetPath, constraintYAML, templateYAML, alphaYAML, betaYAML string, inventoryYAMLs []string, feedback string) string {
 	constraint := truncateString(constraintYAML, 3000)
 	template := truncateString(templateYAML, 3000)
 	alphaBlock := fmt.Sprintf("```yaml\n%s\n```", strings.TrimSpace(alphaYAML))
@@ -122,6 +296,12 @@ func buildRepairPrompt(taskID, targetPath, constraintYAML, templateYAML, alphaYA
 		inventorySection = fmt.Sprintf("\nInventory (existing resources in cluster that beta may need to conflict with):\n%s\n", strings.Join(invBlocks, "\n"))
 	}
 
+	var feedbackSection string
+	if feedback != "" {
+		feedbackSection = fmt.Sprintf("\nYour previous attempt was checked against the real policy and didn't work out:\n%s\n"+
+			"Take this into account and produce a new diff against the CURRENT beta manifest shown below.\n", feedback)
+	}
+
 	prompt := fmt.Sprintf(
 		"You are editing Kubernetes manifests for a Gatekeeper policy benchmark.\n\n"+
 			"Task: Modify ONLY the beta manifest so it VIOLATES the policy. The alpha manifest is COMPLIANT and must stay unchanged.\n\n"+
@@ -134,13 +314,15 @@ func buildRepairPrompt(taskID, targetPath, constraintYAML, templateYAML, alphaYA
 			"Preserve metadata.name, metadata.namespace, and all labels.\n"+
 			"Return a unified diff ONLY for the beta manifest.\n"+
 			"Use the following file path in the diff header: %s\n"+
-			"If the beta manifest already violates the policy, respond with NO_CHANGES.\n\n"+
+			"If the beta manifest already violates the policy, respond with NO_CHANGES.\n"+
+			"%s\n"+
 			"Constraint:\n%s\n\n"+
 			"Template:\n%s\n"+
 			"%s\n"+
 			"Alpha manifest (COMPLIANT - do not change):\n%s\n\n"+
 			"Beta manifest (must VIOLATE policy - modify this only):\n%s\n",
 		targetPath,
+		feedbackSection,
 		constraint,
 		template,
 		inventorySection,
@@ -193,33 +375,6 @@ func normalizeDiff(diffText, targetPath string) string {
 	return strings.Join(lines, "\n") + "\n"
 }
 
-func applyPatch(diff string) error {
-	cmd := exec.Command("patch", "-p0", "-u", "-i", "-")
-	cmd.Stdin = strings.NewReader(diff)
-	var output bytes.Buffer
-	cmd.Stdout = &output
-	cmd.Stderr = &output
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("patch failed: %s", strings.TrimSpace(output.String()))
-	}
-	return nil
-}
-
-func extractGeminiText(result *genai.GenerateContentResponse) (string, error) {
-	if result == nil || len(result.Candidates) == 0 {
-		return "", fmt.Errorf("empty response from Gemini")
-	}
-	content := result.Candidates[0].Content
-	if content == nil || len(content.Parts) == 0 {
-		return "", fmt.Errorf("empty response from Gemini")
-	}
-	text := content.Parts[0].Text
-	if strings.TrimSpace(text) == "" {
-		return "", fmt.Errorf("empty response from Gemini")
-	}
-	return strings.TrimSpace(text), nil
-}
-
 func writeRepairReport(outputDir string, results []RepairResult) error {
 	var b strings.Builder
 
@@ -228,13 +383,15 @@ func writeRepairReport(outputDir string, results []RepairResult) error {
 	b.WriteString(fmt.Sprintf("Generated: %s\n\n", time.Now().Format("2006-01-02 15:04:05")))
 
 	// Count stats
-	var repaired, noChanges, errors int
+	var repaired, noChanges, exhausted, errors int
 	for _, r := range results {
 		switch r.Status {
 		case "repaired":
 			repaired++
 		case "no_changes":
 			noChanges++
+		case "exhausted":
+			exhausted++
 		case "error":
 			errors++
 		}
@@ -246,6 +403,7 @@ func writeRepairReport(outputDir string, results []RepairResult) error {
 	b.WriteString("|--------|-------|\n")
 	b.WriteString(fmt.Sprintf("| Repaired | %d |\n", repaired))
 	b.WriteString(fmt.Sprintf("| No Changes | %d |\n", noChanges))
+	b.WriteString(fmt.Sprintf("| Exhausted | %d |\n", exhausted))
 	b.WriteString(fmt.Sprintf("| Errors | %d |\n", errors))
 	b.WriteString("\n---\n\n")
 
@@ -256,6 +414,7 @@ func writeRepairReport(outputDir string, results []RepairResult) error {
 			if r.Status == "repaired" {
 				b.WriteString(fmt.Sprintf("### %s\n\n", r.TaskID))
 				b.WriteString(fmt.Sprintf("**File:** `%s`\n\n", r.FilePath))
+				writeAttemptsTrajectory(&b, r.Attempts)
 				b.WriteString("```diff\n")
 				b.WriteString(r.Diff)
 				if !strings.HasSuffix(r.Diff, "\n") {
@@ -278,6 +437,19 @@ func writeRepairReport(outputDir string, results []RepairResult) error {
 		b.WriteString("\n---\n\n")
 	}
 
+	// Exhausted tasks - every attempt tried but none passed verification
+	if exhausted > 0 {
+		b.WriteString("## Exhausted\n\n")
+		for _, r := range results {
+			if r.Status == "exhausted" {
+				b.WriteString(fmt.Sprintf("### %s\n\n", r.TaskID))
+				writeAttemptsTrajectory(&b, r.Attempts)
+				b.WriteString("\n")
+			}
+		}
+		b.WriteString("---\n\n")
+	}
+
 	// Errors
 	if errors > 0 {
 		b.WriteString("## Errors\n\n")
@@ -297,3 +469,19 @@ func writeRepairReport(outputDir string, results []RepairResult) error {
 	reportPath := filepath.Join(outputDir, "repair-report.md")
 	return os.WriteFile(reportPath, []byte(b.String()), 0644)
 }
+
+// writeAttemptsTrajectory renders a task's repair attempts as a compact
+// table, so a reviewer can see how many rounds of admission feedback it
+// took (or why it never converged) without digging through raw diffs.
+func writeAttemptsTrajectory(b *strings.Builder, attempts []RepairAttempt) {
+	if len(attempts) == 0 {
+		return
+	}
+	b.WriteString("| Attempt | Applied | Full Rewrite | Alpha OK | Beta OK | Feedback |\n")
+	b.WriteString("|---------|---------|--------------|----------|---------|----------|\n")
+	for _, a := range attempts {
+		feedback := strings.ReplaceAll(a.Feedback, "\n", " ")
+		fmt.Fprintf(b, "| %d | %t | %t | %t | %t | %s |\n", a.N, a.Applied, a.FullRewrite, a.AlphaOK, a.BetaOK, feedback)
+	}
+	b.WriteString("\n")
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// RepairLLM is the model backend repairTask drives its verification-guided
+// loop against. GenerateDiff takes a fully-built repair prompt and returns
+// the model's raw response text - a unified diff, a full-file rewrite, or
+// "NO_CHANGES" - for repair.go to interpret; it does not parse or validate
+// the response itself.
+type RepairLLM interface {
+	GenerateDiff(ctx context.Context, prompt string) (string, error)
+	Name() string
+}
+
+// RepairBackendConfig is the per-backend defaults for -repair-backend /
+// REPAIR_BACKEND: which model to call, where to call it, and which env var
+// holds its API key. REPAIR_MODEL and REPAIR_BASE_URL override Model and
+// BaseURL for whichever backend is selected.
+type RepairBackendConfig struct {
+	Model     string
+	BaseURL   string
+	APIKeyEnv string
+}
+
+// defaultRepairBackendConfigs holds the built-in defaults for each backend
+// name accepted by -repair-backend, before REPAIR_MODEL/REPAIR_BASE_URL
+// overrides are applied.
+var defaultRepairBackendConfigs = map[string]RepairBackendConfig{
+	"gemini":    {Model: "gemini-2.5-flash", APIKeyEnv: "GEMINI_API_KEY"},
+	"openai":    {Model: "gpt-4o-mini", BaseURL: "https://api.openai.com/v1", APIKeyEnv: "OPENAI_API_KEY"},
+	"anthropic": {Model: "claude-3-5-haiku-20241022", APIKeyEnv: "ANTHROPIC_API_KEY"},
+	// Ollama serves local models and needs no API key.
+	"ollama": {Model: "llama3.1", BaseURL: "http://localhost:11434"},
+	// fake needs no API key either - it never leaves the process.
+	"fake": {Model: "fake"},
+}
+
+// buildRepairLLM constructs the RepairLLM for cfg.RepairBackend, falling
+// back to "gemini" to match repairTask's original hard-coded behavior.
+// It reuses cfg.GeminiClient when the prompt generators already initialized
+// one, so -repair and prompt generation don't each open their own client.
+func buildRepairLLM(cfg *Config) (RepairLLM, error) {
+	backend := cfg.RepairBackend
+	if backend == "" {
+		backend = "gemini"
+	}
+
+	def, ok := defaultRepairBackendConfigs[backend]
+	if !ok {
+		return nil, fmt.Errorf("unknown -repair-backend %q (want gemini, openai, anthropic, ollama, or fake)", backend)
+	}
+
+	model := def.Model
+	if m := os.Getenv("REPAIR_MODEL"); m != "" {
+		model = m
+	}
+	baseURL := def.BaseURL
+	if b := os.Getenv("REPAIR_BASE_URL"); b != "" {
+		baseURL = b
+	}
+
+	var apiKey string
+	if def.APIKeyEnv != "" {
+		apiKey = os.Getenv(def.APIKeyEnv)
+		if apiKey == "" {
+			return nil, fmt.Errorf("-repair-backend %s requires %s to be set", backend, def.APIKeyEnv)
+		}
+	}
+
+	switch backend {
+	case "gemini":
+		if cfg.GeminiClient == nil {
+			client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+				APIKey:  apiKey,
+				Backend: genai.BackendGeminiAPI,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("initializing Gemini client: %w", err)
+			}
+			cfg.GeminiClient = client
+		}
+		return &GeminiRepairLLM{Client: cfg.GeminiClient, Model: model}, nil
+	case "openai":
+		return &OpenAIRepairLLM{BaseURL: baseURL, APIKey: apiKey, Model: model}, nil
+	case "anthropic":
+		return &AnthropicRepairLLM{APIKey: apiKey, Model: model}, nil
+	case "ollama":
+		return &OllamaRepairLLM{BaseURL: baseURL, Model: model}, nil
+	case "fake":
+		return &FakeRepairLLM{}, nil
+	default:
+		return nil, fmt.Errorf("unknown -repair-backend %q", backend)
+	}
+}
+
+// GeminiRepairLLM drives the repair loop via the Gemini API.
+type GeminiRepairLLM struct {
+	Client *genai.Client
+	Model  string
+}
+
+func (g *GeminiRepairLLM) Name() string { return "gemini:" + g.Model }
+
+func (g *GeminiRepairLLM) GenerateDiff(ctx context.Context, prompt string) (string, error) {
+	result, err := g.Client.Models.GenerateContent(ctx, g.Model, genai.Text(prompt), nil)
+	if err != nil {
+		return "", fmt.Errorf("gemini API error: %w", err)
+	}
+	return extractGeminiText(result)
+}
+
+func extractGeminiText(result *genai.GenerateContentResponse) (string, error) {
+	if result == nil || len(result.Candidates) == 0 {
+		return "", fmt.Errorf("empty response from Gemini")
+	}
+	content := result.Candidates[0].Content
+	if content == nil || len(content.Parts) == 0 {
+		return "", fmt.Errorf("empty response from Gemini")
+	}
+	text := content.Parts[0].Text
+	if strings.TrimSpace(text) == "" {
+		return "", fmt.Errorf("empty response from Gemini")
+	}
+	return strings.TrimSpace(text), nil
+}
+
+// OpenAIRepairLLM drives the repair loop via any OpenAI-compatible chat
+// completions endpoint, selected by pointing BaseURL at it.
+type OpenAIRepairLLM struct {
+	BaseURL string
+	APIKey  string
+	Model   string
+}
+
+func (o *OpenAIRepairLLM) Name() string { return "openai:" + o.Model }
+
+func (o *OpenAIRepairLLM) GenerateDiff(ctx context.Context, prompt string) (string, error) {
+	reqBody, err := json.Marshal(map[string]interface{}{
+		"model": o.Model,
+		"messages": []map[string]string{
+			{"role": "user", "content": prompt},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(o.BaseURL, "/")+"/chat/completions", bytes.NewReader(reqBody))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.APIKey)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("openai-compatible API error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("openai-compatible API returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var parsed struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return "", fmt.Errorf("parsing openai-compatible response: %w", err)
+	}
+	if len(parsed.Choices) == 0 || parsed.Choices[0].Message.Content == "" {
+		return "", fmt.Errorf("empty response from openai-compatible API")
+	}
+	return strings.TrimSpace(parsed.Choices[0].Message.Content), nil
+}
+
+// AnthropicRepairLLM drives the repair loop via the Anthropic Messages API.
+type AnthropicRepairLLM struct {
+	APIKey string
+	Model  string
+}
+
+func (a *AnthropicRepairLLM) Name() string { return "anthropic:" + a.Model }
+
+func (a *AnthropicRepairLLM) GenerateDiff(ctx context.Context, prompt string) (string, error) {
+	reqBody, err := json.Marshal(map[string]interface{}{
+		"model":      a.Model,
+		"max_tokens": 4096,
+		"messages": []map[string]string{
+			{"role": "user", "content": prompt},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.anthropic.com/v1/messages", bytes.NewReader(reqBody))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", a.APIKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("anthropic API error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("anthropic API returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var parsed struct {
+		Content []struct {
+			Text string `json:"text"`
+		} `json:"content"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return "", fmt.Errorf("parsing anthropic response: %w", err)
+	}
+	if len(parsed.Content) == 0 || parsed.Content[0].Text == "" {
+		return "", fmt.Errorf("empty response from Anthropic")
+	}
+	return strings.TrimSpace(parsed.Content[0].Text), nil
+}
+
+// OllamaRepairLLM drives the repair loop via a local Ollama server's chat
+// API, so repair quality can be benchmarked against local models without
+// any API key.
+type OllamaRepairLLM struct {
+	BaseURL string
+	Model   string
+}
+
+func (o *OllamaRepairLLM) Name() string { return "ollama:" + o.Model }
+
+func (o *OllamaRepairLLM) GenerateDiff(ctx context.Context, prompt string) (string, error) {
+	reqBody, err := json.Marshal(map[string]interface{}{
+		"model":  o.Model,
+		"stream": false,
+		"messages": []map[string]string{
+			{"role": "user", "content": prompt},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(o.BaseURL, "/")+"/api/chat", bytes.NewReader(reqBody))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("ollama API error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("ollama API returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var parsed struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return "", fmt.Errorf("parsing ollama response: %w", err)
+	}
+	if parsed.Message.Content == "" {
+		return "", fmt.Errorf("empty response from ollama")
+	}
+	return strings.TrimSpace(parsed.Message.Content), nil
+}
+
+// FakeRepairLLM returns a canned "no changes needed" response without any
+// network call, so -repair-backend=fake can exercise repairTask's loop in
+// CI with no API key.
+type FakeRepairLLM struct{}
+
+func (f *FakeRepairLLM) Name() string { return "fake" }
+
+func (f *FakeRepairLLM) GenerateDiff(ctx context.Context, prompt string) (string, error) {
+	return "NO_CHANGES", nil
+}
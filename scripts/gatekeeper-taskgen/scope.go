@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"k8s.io/client-go/discovery"
+)
+
+// apiResourceDump mirrors the subset of a cached `kubectl api-resources -o
+// json` (or a DiscoveryClient resource dump) that we need to answer scope
+// questions offline, similar to how Beyla's k8s-cache informer store keeps a
+// flat snapshot of cluster shape around for lookups without a live client.
+type apiResourceDump struct {
+	Resources []struct {
+		GroupVersion string `json:"groupVersion"`
+		Kind         string `json:"kind"`
+		Namespaced   bool   `json:"namespaced"`
+	} `json:"resources"`
+}
+
+// ScopeResolver answers "is this apiVersion/kind namespaced or cluster
+// scoped?". It prefers live discovery against a real cluster, falls back to
+// a cached api-resources.json snapshot, and finally falls back to the static
+// clusterScopedKinds list when neither is available. This replaces the
+// previous divergent isClusterScoped/clusterScopedKinds implementations with
+// a single source of truth.
+type ScopeResolver struct {
+	namespaced map[string]bool // "apiVersion/Kind" -> namespaced
+}
+
+// NewScopeResolver builds a resolver from a discovery client. client may be
+// nil, in which case the resolver falls back to cachePath (if non-empty) and
+// ultimately to the static clusterScopedKinds list.
+func NewScopeResolver(client discovery.DiscoveryInterface, cachePath string) *ScopeResolver {
+	r := &ScopeResolver{namespaced: map[string]bool{}}
+	if client != nil {
+		r.loadFromDiscovery(client)
+	} else if cachePath != "" {
+		r.loadFromFile(cachePath)
+	}
+	return r
+}
+
+func (r *ScopeResolver) loadFromDiscovery(client discovery.DiscoveryInterface) {
+	_, lists, err := client.ServerGroupsAndResources()
+	if err != nil {
+		return
+	}
+	for _, list := range lists {
+		for _, res := range list.APIResources {
+			r.namespaced[list.GroupVersion+"/"+res.Kind] = res.Namespaced
+		}
+	}
+}
+
+func (r *ScopeResolver) loadFromFile(cachePath string) {
+	data, err := os.ReadFile(cachePath)
+	if err != nil {
+		return
+	}
+	var dump apiResourceDump
+	if json.Unmarshal(data, &dump) != nil {
+		return
+	}
+	for _, res := range dump.Resources {
+		r.namespaced[res.GroupVersion+"/"+res.Kind] = res.Namespaced
+	}
+}
+
+// IsClusterScoped reports whether kind (as served under apiVersion) is
+// cluster-scoped. apiVersion may be empty, in which case only the static
+// fallback list applies. A nil receiver is valid and always falls back.
+func (r *ScopeResolver) IsClusterScoped(apiVersion, kind string) bool {
+	if r != nil && apiVersion != "" {
+		if namespaced, ok := r.namespaced[apiVersion+"/"+kind]; ok {
+			return !namespaced
+		}
+	}
+	return isClusterScopedKind(kind)
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// SkipRule is one entry in SelectionConfig.Skip: a pattern matched against
+// a task's SuiteName/TestName, with the reason and category recorded so
+// selection-report.json can explain *why* a task was dropped instead of
+// just that it was.
+type SkipRule struct {
+	Pattern    string   `json:"pattern" yaml:"pattern"`
+	Reason     string   `json:"reason,omitempty" yaml:"reason,omitempty"`
+	Categories []string `json:"categories,omitempty" yaml:"categories,omitempty"`
+}
+
+// TaskOverride customizes generation for one task ID on top of cfg's
+// generator-wide defaults. Timeout uses Go duration syntax (e.g. "90s"), the
+// syntax cfg's own *Timeout flags take, and is parsed by resolveTaskOverride
+// when the task is generated; a value that does not parse leaves
+// cfg.TaskTimeout in place.
+type TaskOverride struct {
+	Timeout        string   `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	Namespace      string   `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	ExtraInventory []string `json:"extraInventory,omitempty" yaml:"extra-inventory,omitempty"`
+}
+
+// CategoriesConfig lets a -config file re-enable skip rules wholesale by
+// category instead of having to repeat every pattern in Only.
+type CategoriesConfig struct {
+	Disable []string `json:"disable,omitempty" yaml:"disable,omitempty"`
+}
+
+// SelectionConfig is the -config file's schema: which tasks to skip or
+// restrict generation to, and per-task overrides. shouldSkip matches Skip
+// and Only patterns as glob (via filepath.Match) or, prefixed "regex:", a
+// regular expression - in addition to the exact-match/substring check the
+// generator has always done.
+type SelectionConfig struct {
+	Skip       []SkipRule              `json:"skip,omitempty" yaml:"skip,omitempty"`
+	Only       []string                `json:"only,omitempty" yaml:"only,omitempty"`
+	Overrides  map[string]TaskOverride `json:"overrides,omitempty" yaml:"overrides,omitempty"`
+	Categories CategoriesConfig        `json:"categories,omitempty" yaml:"categories,omitempty"`
+}
+
+//go:embed default_selection.yaml
+var defaultSelectionYAML []byte
+
+// loadSelectionConfig parses path as a SelectionConfig, or - if path is
+// empty - the embedded default_selection.yaml, so behavior is unchanged
+// from the old hard-coded defaultSkipList when no -config is passed.
+func loadSelectionConfig(path string) (SelectionConfig, error) {
+	data := defaultSelectionYAML
+	if path != "" {
+		var err error
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return SelectionConfig{}, fmt.Errorf("reading -config %s: %w", path, err)
+		}
+	}
+	var cfg SelectionConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return SelectionConfig{}, fmt.Errorf("parsing selection config: %w", err)
+	}
+	return cfg, nil
+}
+
+// SelectionDecision is shouldSkip's verdict for one task, recorded verbatim
+// in selection-report.json so a -config change's effect on the library is
+// auditable without re-running the generator in -verbose mode.
+type SelectionDecision struct {
+	TaskID      string   `json:"taskID"`
+	Skip        bool     `json:"skip"`
+	MatchedRule string   `json:"matchedRule,omitempty"`
+	Reason      string   `json:"reason,omitempty"`
+	Categories  []string `json:"categories,omitempty"`
+}
+
+// matchesPattern reports whether pattern selects testName/suiteName. It
+// tries, in order: exact match, substring match against testName (the
+// generator's original -skip behavior), then either - for a "regex:"
+// prefixed pattern - a regular expression against either name, or else a
+// shell glob (filepath.Match) against either name.
+func matchesPattern(pattern, suiteName, testName string) bool {
+	if pattern == suiteName || pattern == testName || strings.Contains(testName, pattern) {
+		return true
+	}
+	if re, ok := strings.CutPrefix(pattern, "regex:"); ok {
+		rx, err := regexp.Compile(re)
+		return err == nil && (rx.MatchString(testName) || rx.MatchString(suiteName))
+	}
+	if ok, err := filepath.Match(pattern, testName); err == nil && ok {
+		return true
+	}
+	if ok, err := filepath.Match(pattern, suiteName); err == nil && ok {
+		return true
+	}
+	return false
+}
+
+// shouldSkip decides whether task should be generated, preferring
+// cfg.SelectionConfig over the old SkipList substring check it replaced:
+// first Only (if set, a task must match one entry to be kept), then Skip
+// rules (skipped unless every one of the rule's categories is disabled via
+// Categories.Disable), then the original alpha/beta completeness check.
+func shouldSkip(cfg Config, task TaskMetadata) SelectionDecision {
+	d := SelectionDecision{TaskID: task.TaskID}
+	sel := cfg.SelectionConfig
+
+	if len(sel.Only) > 0 {
+		matched := slices.ContainsFunc(sel.Only, func(p string) bool {
+			return matchesPattern(p, task.SuiteName, task.TestName)
+		})
+		if !matched {
+			d.Skip = true
+			d.Reason = "not in -config only list"
+			return d
+		}
+	}
+
+	for _, rule := range sel.Skip {
+		if !matchesPattern(rule.Pattern, task.SuiteName, task.TestName) {
+			continue
+		}
+		if len(rule.Categories) > 0 && categoriesAllDisabled(sel.Categories, rule.Categories) {
+			continue
+		}
+		d.Skip = true
+		d.MatchedRule = rule.Pattern
+		d.Reason = rule.Reason
+		d.Categories = rule.Categories
+		return d
+	}
+
+	alpha, beta := 0, 0
+	for _, c := range task.Cases {
+		if c.Expected == "alpha" {
+			alpha++
+		} else {
+			beta++
+		}
+	}
+	if alpha == 0 || beta == 0 {
+		d.Skip = true
+		d.Reason = fmt.Sprintf("missing alpha or beta cases (alpha=%d beta=%d)", alpha, beta)
+	}
+	return d
+}
+
+// categoriesAllDisabled reports whether every one of ruleCategories is
+// listed in disabled - i.e. the user has opted back into every category
+// this skip rule belongs to, so the rule no longer applies.
+func categoriesAllDisabled(cfg CategoriesConfig, ruleCategories []string) bool {
+	for _, c := range ruleCategories {
+		if !slices.Contains(cfg.Disable, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveTaskOverride looks up taskID's TaskOverride (the zero value if
+// none is configured) and applies its Timeout/Namespace on top of cfg's
+// generator-wide defaults.
+func resolveTaskOverride(cfg Config, taskID string) (timeout time.Duration, namespace string, extraInventory []string) {
+	timeout, namespace = cfg.TaskTimeout, "gk-"+taskID
+	override, ok := cfg.SelectionConfig.Overrides[taskID]
+	if !ok {
+		return timeout, namespace, nil
+	}
+	if override.Timeout != "" {
+		if d, err := time.ParseDuration(override.Timeout); err == nil {
+			timeout = d
+		}
+	}
+	if override.Namespace != "" {
+		namespace = override.Namespace
+	}
+	return timeout, namespace, override.ExtraInventory
+}
+
+// copyExtraInventory copies an override's extra-inventory files into
+// outDir/artifacts as inventory-extra-NN.yaml, the same naming convention
+// setup.sh's `inventory-*.yaml` glob already applies, and returns their
+// artifacts/-relative paths for writeSuite to add to every case.
+func copyExtraInventory(outDir string, files []string) ([]string, error) {
+	var rel []string
+	for i, f := range files {
+		name := fmt.Sprintf("inventory-extra-%02d.yaml", i+1)
+		if err := copyFile(f, filepath.Join(outDir, "artifacts", name)); err != nil {
+			return nil, fmt.Errorf("copying extra-inventory file %s: %w", f, err)
+		}
+		rel = append(rel, filepath.Join("artifacts", name))
+	}
+	return rel, nil
+}
+
+// writeSelectionReport writes selection-report.json next to outputDir's
+// generated tasks, recording shouldSkip's decision for every task the
+// generator looked at, not just the ones it kept.
+func writeSelectionReport(outputDir string, decisions []SelectionDecision) error {
+	data, err := json.MarshalIndent(decisions, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(outputDir, "selection-report.json"), data, 0644)
+}
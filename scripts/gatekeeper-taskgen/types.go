@@ -1,14 +1,82 @@
 package main
 
-import "google.golang.org/genai"
+import (
+	"time"
+
+	"google.golang.org/genai"
+)
 
 // Config holds generator configuration
 type Config struct {
-	LibraryRoot  string
-	OutputDir    string
-	SkipList     []string
-	Verbose      bool
-	GeminiClient *genai.Client
+	LibraryRoot string
+	OutputDir   string
+	// SelectionConfig decides which tasks shouldSkip drops and which get
+	// per-task overrides, loaded by loadSelectionConfig from -config (or
+	// the embedded default_selection.yaml if -config is unset) and then
+	// extended with any repeated -skip flags.
+	SelectionConfig  SelectionConfig
+	Verbose          bool
+	Repair           bool
+	Verify           bool
+	GeminiClient     *genai.Client
+	PromptGenerators []PromptGenerator
+	// Ensemble generates one candidate prompt per configured
+	// PromptGenerator and keeps the highest-scoring one (see scorePrompt),
+	// instead of using the first generator that succeeds.
+	Ensemble bool
+	// AIBackend restricts prompt generation to a single backend ("gemini",
+	// "openai", "anthropic", or "fake") instead of the default of enabling
+	// every backend with an API key present. Empty means the default.
+	AIBackend     string
+	ScopeResolver *ScopeResolver
+	// MaxRepairAttempts bounds repairTask's verification-guided repair
+	// loop: how many times it will ask the LLM for a new diff after a
+	// candidate fails the admission check before giving up.
+	MaxRepairAttempts int
+	// RepairBackend selects which RepairLLM buildRepairLLM constructs for
+	// -repair ("gemini", "openai", "anthropic", "ollama", or "fake"). Empty means
+	// "gemini", matching repairTask's original hard-coded behavior.
+	RepairBackend string
+	// RepairLLM is the backend repairTask drives its repair loop against,
+	// built from RepairBackend by buildRepairLLM.
+	RepairLLM RepairLLM
+	// TaskTimeout is written into the generated task.yaml's top-level
+	// timeout field - how long a harness gets to run the whole task.
+	TaskTimeout time.Duration
+	// ApplyTimeout bounds the generated setup.sh's task-apply call (one
+	// deadline shared by every object it waits on), and each object's
+	// waitForReady during -verify.
+	ApplyTimeout time.Duration
+	// RepairTimeout bounds repairTask's whole verification-guided loop
+	// (every attempt, not just one LLM call). Validated at startup to be
+	// >= ApplyTimeout, since a repair attempt needs to observe a failing
+	// apply to have feedback to act on.
+	RepairTimeout time.Duration
+	// NamespaceReadyTimeout bounds the generated setup.sh's wait for the
+	// task namespace to become Active.
+	NamespaceReadyTimeout time.Duration
+	// CleanupTimeout bounds the generated cleanup.sh's task-cleanup call:
+	// the grace deadline for evicted pods to actually disappear before
+	// cleanup gives up and moves on to deleting the namespace anyway.
+	CleanupTimeout time.Duration
+	// Jobs is how many generateTask calls run() dispatches concurrently.
+	Jobs int
+	// AIConcurrency bounds concurrent AI calls (prompt generation and
+	// repair) across every worker, independent of Jobs, via AILimiter.
+	AIConcurrency int
+	// AILimiter enforces AIConcurrency; built from it once in main.
+	AILimiter *aiLimiter
+	// FailFast cancels the remaining task generation on the first
+	// generateTask error (not a shouldSkip skip or a failed -verify, which
+	// are both expected outcomes, not failures) instead of continuing
+	// through the rest of the library.
+	FailFast bool
+	// Log is generateTask's per-task log sink. run()'s -jobs worker pool
+	// overrides it per task with a buffered writer so concurrent tasks'
+	// log lines don't interleave, then flushes each task's buffer once
+	// its turn comes up in sorted order. Defaults to printing straight to
+	// stdout.
+	Log func(format string, args ...interface{})
 }
 
 // Suite represents a gatekeeper suite.yaml file
@@ -81,17 +149,38 @@ type TaskArtifacts struct {
 	Manifests        []TaskManifest
 	CaseFiles        map[string][]string
 	InventoryFiles   map[string][]string
+	AdmissionFiles   map[string][]string // case name -> admission/*.json paths, for cases sourced from AdmissionReview fixtures
 	Namespaces       []string
 	ClusterResources []ClusterResource
+	// ExtraInventory holds artifacts/ relative paths for inventory files
+	// added by a -config override's extra-inventory list, on top of
+	// whatever GenerateManifests derived from the suite itself.
+	ExtraInventory []string
 }
 
 // PromptContext holds all context needed to generate a prompt
 type PromptContext struct {
-	TaskID         string
-	Title          string
-	Description    string
-	TemplateYAML   string
-	ConstraintYAML string
-	AlphaExamples  []string
-	BetaExamples   []string
+	TaskID          string
+	Title           string
+	Description     string
+	TemplateYAML    string
+	ConstraintYAML  string
+	AlphaExamples   []string
+	BetaExamples    []string
+	Namespace       string
+	NamespacedKinds []string
+	ClusterKinds    []string
+	// HasAdmissionCases is true when at least one case came from an
+	// AdmissionReview fixture, so the generated task also ships an
+	// admission/ directory an evaluator can POST straight to Gatekeeper.
+	HasAdmissionCases bool
+	// IsMutationTask is true for a gk-mutation-* task, whose prompt asks
+	// the AI to identify which fields a mutation policy should change
+	// rather than which resources violate a constraint.
+	IsMutationTask bool
+	// MutationInputYAML is the pre-mutation resource shown to the AI. The
+	// mutator and expected post-mutation resource are deliberately left
+	// out of the prompt context so the AI has to reason about the policy
+	// description rather than read off the answer.
+	MutationInputYAML string
 }
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// diffLine is one line of a hunk body: ' ' (context), '-' (removed from the
+// original) or '+' (added). text excludes the leading marker.
+type diffLine struct {
+	kind byte
+	text string
+}
+
+// diffHunk is one "@@ -oldStart,oldLines +newStart,newLines @@" block.
+type diffHunk struct {
+	oldStart, oldLines int
+	newStart, newLines int
+	lines              []diffLine
+}
+
+var hunkHeaderRe = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// parseUnifiedDiff splits a single-file unified diff into hunks. File
+// header lines ("--- a" / "+++ b") are skipped; we only ever apply diffs
+// against one known target path, so the header's own path is irrelevant.
+func parseUnifiedDiff(diffText string) ([]diffHunk, error) {
+	lines := strings.Split(diffText, "\n")
+	i := 0
+	for i < len(lines) && !strings.HasPrefix(lines[i], "@@") {
+		i++
+	}
+
+	var hunks []diffHunk
+	for i < len(lines) {
+		m := hunkHeaderRe.FindStringSubmatch(lines[i])
+		if m == nil {
+			return nil, fmt.Errorf("invalid hunk header: %q", lines[i])
+		}
+		h := diffHunk{
+			oldStart: atoiDefault(m[1], 0),
+			oldLines: atoiDefault(m[2], 1),
+			newStart: atoiDefault(m[3], 0),
+			newLines: atoiDefault(m[4], 1),
+		}
+		i++
+
+		for i < len(lines) && !strings.HasPrefix(lines[i], "@@") {
+			l := lines[i]
+			switch {
+			case l == "" && i == len(lines)-1:
+				// Trailing blank line from a final newline in the diff text.
+			case strings.HasPrefix(l, "\\"):
+				// "\ No newline at end of file" - doesn't affect application.
+			case strings.HasPrefix(l, "+"):
+				h.lines = append(h.lines, diffLine{kind: '+', text: l[1:]})
+			case strings.HasPrefix(l, "-"):
+				h.lines = append(h.lines, diffLine{kind: '-', text: l[1:]})
+			case strings.HasPrefix(l, " "):
+				h.lines = append(h.lines, diffLine{kind: ' ', text: l[1:]})
+			default:
+				// A context line missing its leading space is common LLM
+				// sloppiness; treat it as context rather than rejecting
+				// the whole hunk over whitespace.
+				h.lines = append(h.lines, diffLine{kind: ' ', text: l})
+			}
+			i++
+		}
+		hunks = append(hunks, h)
+	}
+
+	if len(hunks) == 0 {
+		return nil, fmt.Errorf("no hunks found in diff")
+	}
+	return hunks, nil
+}
+
+func atoiDefault(s string, def int) int {
+	if s == "" {
+		return def
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// hunkApplyError is returned when a hunk's context can't be located in the
+// target file even within the fuzz window, identifying which hunk failed
+// and the region it resembles most closely so a caller can report or
+// re-prompt with something more actionable than "patch failed".
+type hunkApplyError struct {
+	HunkIndex   int
+	OldStart    int
+	ClosestLine int
+	Reason      string
+}
+
+func (e *hunkApplyError) Error() string {
+	return fmt.Sprintf("hunk %d (expected near line %d) %s; closest match starts at line %d",
+		e.HunkIndex+1, e.OldStart, e.Reason, e.ClosestLine)
+}
+
+const hunkFuzz = 3
+
+// applyDiff applies a unified diff to original in memory, locating each
+// hunk by fuzzy-matching its context/deletion lines against the target
+// within ±hunkFuzz lines of where the hunk header claims it starts (GNU
+// patch's own fuzz strategy), and tolerating CRLF/trailing-whitespace
+// drift when comparing lines. Context lines are copied from the original
+// file rather than the diff, so whitespace drift in the diff itself never
+// leaks into the result.
+func applyDiff(original []byte, diffText string) ([]byte, error) {
+	hunks, err := parseUnifiedDiff(diffText)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, endsWithNewline := splitLines(original)
+	delta := 0 // cumulative line-count shift from hunks already applied
+
+	for idx, h := range hunks {
+		search := searchLines(h)
+		expected := h.oldStart - 1 + delta
+
+		offset, ok := findHunkOffset(lines, search, expected, hunkFuzz)
+		if !ok {
+			return nil, &hunkApplyError{
+				HunkIndex:   idx,
+				OldStart:    h.oldStart,
+				ClosestLine: closestOffset(lines, search, expected) + 1,
+				Reason:      "context didn't match",
+			}
+		}
+
+		replacement, consumed := replacementLines(lines, offset, h)
+		lines = spliceLines(lines, offset, consumed, replacement)
+		delta += len(replacement) - consumed
+	}
+
+	return joinLines(lines, endsWithNewline), nil
+}
+
+// searchLines is the sequence of original-file lines a hunk expects to
+// find: its context (' ') and deleted ('-') lines, in order.
+func searchLines(h diffHunk) []string {
+	var out []string
+	for _, l := range h.lines {
+		if l.kind == ' ' || l.kind == '-' {
+			out = append(out, l.text)
+		}
+	}
+	return out
+}
+
+// findHunkOffset looks for search within lines, trying expected first and
+// then increasingly distant offsets up to ±fuzz, matching with
+// normalizeLine so whitespace-only drift doesn't sink an otherwise correct
+// hunk. A hunk with no context/deletion lines (pure insertion) anchors
+// directly at expected, clamped to the file's bounds.
+func findHunkOffset(lines, search []string, expected, fuzz int) (int, bool) {
+	if len(search) == 0 {
+		return clamp(expected, 0, len(lines)), true
+	}
+	for d := 0; d <= fuzz; d++ {
+		for _, cand := range []int{expected - d, expected + d} {
+			if d == 0 && cand != expected {
+				continue
+			}
+			if cand < 0 || cand+len(search) > len(lines) {
+				continue
+			}
+			if blockMatches(lines[cand:cand+len(search)], search) {
+				return cand, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func blockMatches(fileLines, search []string) bool {
+	for i, s := range search {
+		if normalizeLine(fileLines[i]) != normalizeLine(s) {
+			return false
+		}
+	}
+	return true
+}
+
+// closestOffset finds the offset (within a widened window around expected)
+// whose block shares the most matching lines with search, for a
+// hunkApplyError's "closest match" hint.
+func closestOffset(lines, search []string, expected int) int {
+	if len(search) == 0 || len(lines) == 0 {
+		return clamp(expected, 0, len(lines))
+	}
+	span := hunkFuzz * 8
+	bestOffset, bestScore := clamp(expected, 0, max(0, len(lines)-len(search))), -1
+	for cand := max(0, expected-span); cand <= min(len(lines)-len(search), expected+span); cand++ {
+		score := 0
+		for i, s := range search {
+			if normalizeLine(lines[cand+i]) == normalizeLine(s) {
+				score++
+			}
+		}
+		if score > bestScore {
+			bestOffset, bestScore = cand, score
+		}
+	}
+	return bestOffset
+}
+
+// replacementLines walks a hunk against lines[offset:], pulling context
+// lines from the original file (not the diff) and deletions from it
+// (dropped), and appending the hunk's own added lines. It returns the
+// lines to splice in and how many original lines they replace.
+func replacementLines(lines []string, offset int, h diffHunk) (replacement []string, consumed int) {
+	pos := offset
+	for _, l := range h.lines {
+		switch l.kind {
+		case ' ':
+			replacement = append(replacement, lines[pos])
+			pos++
+		case '-':
+			pos++
+		case '+':
+			replacement = append(replacement, l.text)
+		}
+	}
+	return replacement, pos - offset
+}
+
+func spliceLines(lines []string, offset, consumed int, replacement []string) []string {
+	out := make([]string, 0, len(lines)-consumed+len(replacement))
+	out = append(out, lines[:offset]...)
+	out = append(out, replacement...)
+	out = append(out, lines[offset+consumed:]...)
+	return out
+}
+
+// normalizeLine makes line comparison tolerant of CRLF and trailing
+// whitespace drift, the most common noise in LLM-generated diffs.
+func normalizeLine(s string) string {
+	return strings.TrimRight(strings.TrimSuffix(s, "\r"), " \t")
+}
+
+func splitLines(data []byte) (lines []string, endsWithNewline bool) {
+	text := string(data)
+	endsWithNewline = strings.HasSuffix(text, "\n")
+	trimmed := strings.TrimSuffix(text, "\n")
+	if trimmed == "" {
+		return nil, endsWithNewline
+	}
+	return strings.Split(trimmed, "\n"), endsWithNewline
+}
+
+func joinLines(lines []string, endsWithNewline bool) []byte {
+	out := strings.Join(lines, "\n")
+	if endsWithNewline {
+		out += "\n"
+	}
+	return []byte(out)
+}
+
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// VerifyResult records whether one generated task's artifacts actually
+// deploy and enforce the way task.yaml claims they do.
+type VerifyResult struct {
+	TaskID    string   `json:"taskID"`
+	Passed    bool     `json:"passed"`
+	AlphaErr  string   `json:"alphaError,omitempty"`
+	BetaErr   string   `json:"betaError,omitempty"`
+	Denied    []string `json:"denied,omitempty"` // beta files Gatekeeper actually denied
+	NotDenied []string `json:"notDenied,omitempty"`
+}
+
+// VerifyReport is written to verification.json next to the generated tasks.
+type VerifyReport struct {
+	GeneratedAt time.Time      `json:"generatedAt"`
+	Results     []VerifyResult `json:"results"`
+}
+
+// verifyCluster holds the handle to the kind cluster used for verification.
+// It's created once per generator run and reused across tasks so we don't
+// pay cluster/Gatekeeper startup cost per task.
+type verifyCluster struct {
+	kubeconfig string
+	ownsKind   bool // true if we created the cluster and should tear it down
+}
+
+const verifyKindClusterName = "k8s-ai-bench-verify"
+
+// setupVerifyCluster reuses the cluster $KUBECONFIG points at if it is set
+// and reachable, otherwise spins up a disposable one named
+// verifyKindClusterName and installs Gatekeeper into it. Mirrors Helm's
+// kube-client Wait/readiness model: every step below blocks until the
+// underlying objects are actually ready, not just "applied".
+func setupVerifyCluster() (*verifyCluster, error) {
+	if kubeconfig := os.Getenv("KUBECONFIG"); kubeconfig != "" {
+		if err := runKubectl(kubeconfig, "cluster-info"); err == nil {
+			return &verifyCluster{kubeconfig: kubeconfig}, nil
+		}
+	}
+
+	if err := runCmd("kind", "get", "clusters"); err != nil {
+		return nil, fmt.Errorf("kind not available: %w", err)
+	}
+
+	vc := &verifyCluster{ownsKind: true}
+	if err := runCmd("kind", "create", "cluster", "--name", verifyKindClusterName); err != nil {
+		return nil, fmt.Errorf("creating kind cluster: %w", err)
+	}
+
+	kubeconfigPath := filepath.Join(os.TempDir(), verifyKindClusterName+".kubeconfig")
+	if err := runCmdOut(kubeconfigPath, "kind", "get", "kubeconfig", "--name", verifyKindClusterName); err != nil {
+		return nil, fmt.Errorf("writing kubeconfig: %w", err)
+	}
+	vc.kubeconfig = kubeconfigPath
+
+	if err := runKubectl(vc.kubeconfig, "apply", "-f",
+		"https://raw.githubusercontent.com/open-policy-agent/gatekeeper/master/deploy/gatekeeper.yaml"); err != nil {
+		return nil, fmt.Errorf("installing gatekeeper: %w", err)
+	}
+	if err := runKubectl(vc.kubeconfig, "wait", "--for=condition=Available", "--timeout=180s",
+		"-n", "gatekeeper-system", "deployment/gatekeeper-controller-manager"); err != nil {
+		return nil, fmt.Errorf("waiting for gatekeeper: %w", err)
+	}
+
+	return vc, nil
+}
+
+// Teardown deletes the kind cluster if setupVerifyCluster created it.
+func (vc *verifyCluster) Teardown() {
+	if vc == nil || !vc.ownsKind {
+		return
+	}
+	_ = runCmd("kind", "delete", "cluster", "--name", verifyKindClusterName)
+}
+
+// verifyTask installs outDir's ConstraintTemplate/Constraint, applies its
+// inventory and alpha manifests and polls for readiness, then dry-run
+// applies every beta manifest and asserts Gatekeeper denies it.
+func verifyTask(vc *verifyCluster, outDir, taskID string, artifacts TaskArtifacts, applyTimeout time.Duration) VerifyResult {
+	result := VerifyResult{TaskID: taskID, Passed: true}
+
+	ns := "gk-" + taskID
+	if err := runKubectl(vc.kubeconfig, "create", "namespace", ns, "--dry-run=client", "-o", "yaml"); err == nil {
+		_ = runKubectl(vc.kubeconfig, "apply", "-f", "-")
+	}
+	_ = runKubectl(vc.kubeconfig, "apply", "-f", filepath.Join(outDir, "template.yaml"))
+	if err := runKubectl(vc.kubeconfig, "apply", "-f", filepath.Join(outDir, "constraint.yaml")); err != nil {
+		result.Passed = false
+		result.AlphaErr = fmt.Sprintf("applying constraint: %v", err)
+		return result
+	}
+
+	for _, m := range artifacts.Manifests {
+		if m.Expected == "beta" {
+			continue
+		}
+		if err := runKubectl(vc.kubeconfig, "apply", "-f", filepath.Join(outDir, m.RelPath)); err != nil {
+			result.Passed = false
+			result.AlphaErr = fmt.Sprintf("applying %s: %v", m.RelPath, err)
+			continue
+		}
+		if err := waitForReady(vc.kubeconfig, m, applyTimeout); err != nil {
+			result.Passed = false
+			result.AlphaErr = fmt.Sprintf("%s never became ready: %v", m.RelPath, err)
+		}
+	}
+
+	for _, m := range artifacts.Manifests {
+		if m.Expected != "beta" {
+			continue
+		}
+		out, err := kubectlOutput(vc.kubeconfig, "apply", "--dry-run=server", "-f", filepath.Join(outDir, m.RelPath))
+		denied := err != nil && strings.Contains(strings.ToLower(out), "denied")
+		if denied {
+			result.Denied = append(result.Denied, m.RelPath)
+		} else {
+			result.NotDenied = append(result.NotDenied, m.RelPath)
+			result.Passed = false
+		}
+	}
+
+	return result
+}
+
+// waitForReady polls typed readiness for Deployment, StatefulSet, DaemonSet,
+// Pod and PersistentVolumeClaim; other kinds are treated as ready once applied.
+func waitForReady(kubeconfig string, m TaskManifest, timeout time.Duration) error {
+	timeoutArg := fmt.Sprintf("--timeout=%s", timeout)
+	switch m.Kind {
+	case "Deployment", "StatefulSet", "DaemonSet":
+		return runKubectl(kubeconfig, "rollout", "status", "-n", m.Namespace,
+			fmt.Sprintf("%s/%s", strings.ToLower(m.Kind), m.Name), timeoutArg)
+	case "Pod":
+		return runKubectl(kubeconfig, "wait", "--for=condition=Ready", timeoutArg,
+			"-n", m.Namespace, "pod/"+m.Name)
+	case "PersistentVolumeClaim":
+		return runKubectl(kubeconfig, "wait", "--for=jsonpath={.status.phase}=Bound", timeoutArg,
+			"-n", m.Namespace, "pvc/"+m.Name)
+	default:
+		return nil
+	}
+}
+
+// writeVerificationReport writes verification.json next to outputDir's
+// generated tasks.
+func writeVerificationReport(outputDir string, results []VerifyResult) error {
+	report := VerifyReport{GeneratedAt: time.Now(), Results: results}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(outputDir, "verification.json"), data, 0644)
+}
+
+func runCmd(name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+func runCmdOut(outPath, name string, args ...string) error {
+	out, err := exec.Command(name, args...).Output()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outPath, out, 0600)
+}
+
+func runKubectl(kubeconfig string, args ...string) error {
+	_, err := kubectlOutput(kubeconfig, args...)
+	return err
+}
+
+func kubectlOutput(kubeconfig string, args ...string) (string, error) {
+	cmd := exec.Command("kubectl", append([]string{"--kubeconfig", kubeconfig}, args...)...)
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}